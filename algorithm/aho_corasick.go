@@ -0,0 +1,201 @@
+package algorithm
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// ACMatch 一次匹配结果，Start/End 为匹配串在原文中的字节偏移，text[Start:End] == Pattern
+type ACMatch struct {
+	Pattern string
+	Start   int
+	End     int
+}
+
+type acNode struct {
+	next map[rune]int
+	fail int    // 失败指针
+	word string // 以该节点结尾的模式串，空表示不是模式串
+	dict int    // 沿失败链能到达的最近的模式串节点（不含自身），0 表示没有
+}
+
+// acAutomaton 编译后的只读自动机，可被多个 goroutine 同时使用
+type acAutomaton struct {
+	nodes []acNode
+}
+
+// AhoCorasick 多模式串匹配，模式串保存在 TrieTree 中
+// 增删模式串时在写方编译新的自动机，编译完成后原子替换（写时复制），一次 Add/Remove 传入多个模式串只编译一次
+// 匹配只读取当前的自动机，从不参与编译，编译期间正在进行的匹配继续使用旧的自动机，不会被阻塞
+type AhoCorasick struct {
+	mu        sync.Mutex // 串行化写操作
+	patterns  *TrieTree[struct{}]
+	automaton atomic.Pointer[acAutomaton]
+}
+
+func NewAhoCorasick(patterns ...string) *AhoCorasick {
+	ac := &AhoCorasick{patterns: NewTrieTree[struct{}]()}
+	ac.Add(patterns...)
+	return ac
+}
+
+// Add 添加模式串，空串会被忽略
+func (ac *AhoCorasick) Add(patterns ...string) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	for _, p := range patterns {
+		if p != "" {
+			ac.patterns.Insert(p)
+		}
+	}
+	ac.automaton.Store(ac.build())
+}
+
+// Remove 删除模式串
+func (ac *AhoCorasick) Remove(patterns ...string) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	for _, p := range patterns {
+		ac.patterns.Delete(p)
+	}
+	ac.automaton.Store(ac.build())
+}
+
+// Len 返回模式串数量
+func (ac *AhoCorasick) Len() int {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return ac.patterns.Len()
+}
+
+// FindAll 一次线性扫描返回所有匹配（包括相互重叠的匹配），按结束位置排序
+func (ac *AhoCorasick) FindAll(text string) []ACMatch {
+	var matches []ACMatch
+	ac.scan(text, func(m ACMatch) bool {
+		matches = append(matches, m)
+		return true
+	})
+	return matches
+}
+
+// Contains 判断文本中是否包含任意模式串，找到第一个匹配就返回
+func (ac *AhoCorasick) Contains(text string) bool {
+	found := false
+	ac.scan(text, func(m ACMatch) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// Replace 把文本中所有匹配到的字符替换为 mask，重叠的匹配会被合并
+func (ac *AhoCorasick) Replace(text string, mask rune) string {
+	// masked[i] 记录第 i 个字节是否需要被替换
+	masked := make([]bool, len(text))
+	found := false
+	ac.scan(text, func(m ACMatch) bool {
+		found = true
+		for i := m.Start; i < m.End; i++ {
+			masked[i] = true
+		}
+		return true
+	})
+	if !found {
+		return text
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(text))
+	for i := 0; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		if masked[i] {
+			sb.WriteRune(mask)
+		} else {
+			sb.WriteString(text[i : i+size]) // 原样保留，非法的 UTF-8 字节不会被改写
+		}
+		i += size
+	}
+	return sb.String()
+}
+
+// scan 扫描文本，每个匹配调用一次 emit，emit 返回 false 时停止扫描
+// 非法的 UTF-8 字节不与任何字符匹配，匹配到这里中断，保证 text[Start:End] 总是等于模式串
+func (ac *AhoCorasick) scan(text string, emit func(m ACMatch) bool) {
+	a := ac.automaton.Load()
+	state := 0
+	for i, char := range text {
+		if char == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(text[i:]); size == 1 {
+				state = 0
+				continue
+			}
+		}
+		for state != 0 && a.nodes[state].next[char] == 0 {
+			state = a.nodes[state].fail
+		}
+		state = a.nodes[state].next[char] // 不存在时为 0，即回到根节点
+		end := i + utf8.RuneLen(char)
+		for out := state; out > 0; out = a.nodes[out].dict {
+			if word := a.nodes[out].word; word != "" {
+				if !emit(ACMatch{Pattern: word, Start: end - len(word), End: end}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// build 从字典树构建自动机，调用方需持有锁
+func (ac *AhoCorasick) build() *acAutomaton {
+	a := &acAutomaton{nodes: []acNode{{next: map[rune]int{}}}}
+
+	// 复制字典树的结构，节点编号按广度优先顺序分配
+	type pending struct {
+		trie *TrieNode[struct{}]
+		id   int
+		word []rune
+	}
+	queue := []pending{{trie: ac.patterns.root, id: 0}}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for char, child := range cur.trie.children {
+			id := len(a.nodes)
+			word := append(append([]rune(nil), cur.word...), char)
+			node := acNode{next: map[rune]int{}}
+			if child.isEnd {
+				node.word = string(word)
+			}
+			a.nodes = append(a.nodes, node)
+			a.nodes[cur.id].next[char] = id
+			queue = append(queue, pending{trie: child, id: id, word: word})
+		}
+	}
+
+	// 广度优先计算失败指针，父节点的失败指针一定先于子节点计算
+	order := []int{0}
+	for len(order) > 0 {
+		id := order[0]
+		order = order[1:]
+		for char, child := range a.nodes[id].next {
+			fail := 0
+			if id != 0 {
+				f := a.nodes[id].fail
+				for f != 0 && a.nodes[f].next[char] == 0 {
+					f = a.nodes[f].fail
+				}
+				fail = a.nodes[f].next[char]
+			}
+			a.nodes[child].fail = fail
+			if a.nodes[fail].word != "" {
+				a.nodes[child].dict = fail
+			} else {
+				a.nodes[child].dict = a.nodes[fail].dict
+			}
+			order = append(order, child)
+		}
+	}
+	return a
+}
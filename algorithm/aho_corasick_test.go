@@ -0,0 +1,116 @@
+package algorithm
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAhoCorasick_FindAll(t *testing.T) {
+	ac := NewAhoCorasick("he", "she", "his", "hers")
+	matches := ac.FindAll("ushers")
+
+	expected := []ACMatch{
+		{Pattern: "she", Start: 1, End: 4},
+		{Pattern: "he", Start: 2, End: 4},
+		{Pattern: "hers", Start: 2, End: 6},
+	}
+	if fmt.Sprint(matches) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, matches)
+	}
+	for _, m := range matches {
+		if "ushers"[m.Start:m.End] != m.Pattern {
+			t.Errorf("Position of %v does not match the text", m)
+		}
+	}
+}
+
+func TestAhoCorasick_Unicode(t *testing.T) {
+	ac := NewAhoCorasick("敏感", "敏感词", "词")
+	text := "这是一个敏感词测试"
+
+	matches := ac.FindAll(text)
+	if len(matches) != 3 {
+		t.Fatalf("Expected 3 matches, got %v", matches)
+	}
+	for _, m := range matches {
+		if text[m.Start:m.End] != m.Pattern {
+			t.Errorf("Position of %v does not match the text", m)
+		}
+	}
+
+	if got := ac.Replace(text, '*'); got != "这是一个***测试" {
+		t.Errorf("Unexpected replacement %s", got)
+	}
+}
+
+// 非法的 UTF-8 字节不能匹配模式串中的 U+FFFD
+func TestAhoCorasick_InvalidUTF8(t *testing.T) {
+	ac := NewAhoCorasick("\uFFFD", "a\uFFFDb", "ab")
+	text := "\xffa\xffb\uFFFDab"
+
+	matches := ac.FindAll(text)
+	expected := []ACMatch{
+		{Pattern: "\uFFFD", Start: 4, End: 7},
+		{Pattern: "ab", Start: 7, End: 9},
+	}
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, matches)
+	}
+	for i, m := range matches {
+		if m != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], m)
+		}
+	}
+	if ac.Contains("\xff") {
+		t.Error("Invalid bytes should not match")
+	}
+	if got := ac.Replace(text, '*'); got != "\xffa\xffb***" {
+		t.Errorf("Unexpected replacement %q", got)
+	}
+}
+
+func TestAhoCorasick_Incremental(t *testing.T) {
+	ac := NewAhoCorasick("foo")
+	if !ac.Contains("a foo b") {
+		t.Error("Expected foo to match")
+	}
+
+	ac.Add("bar", "baz")
+	ac.Remove("foo")
+	if ac.Len() != 2 {
+		t.Errorf("Expected 2 patterns, got %d", ac.Len())
+	}
+	if ac.Contains("a foo b") {
+		t.Error("Expected foo to be removed")
+	}
+	if got := ac.Replace("foo bar baz", '#'); got != "foo ### ###" {
+		t.Errorf("Unexpected replacement %s", got)
+	}
+	if got := ac.Replace("nothing", '#'); got != "nothing" {
+		t.Errorf("Unexpected replacement %s", got)
+	}
+}
+
+func TestAhoCorasick_CopyOnWrite(t *testing.T) {
+	ac := NewAhoCorasick("foo")
+
+	// 写方持有锁编译时，匹配继续使用当前的自动机，不会被阻塞
+	ac.mu.Lock()
+	done := make(chan bool, 1)
+	go func() { done <- ac.Contains("a foo b") }()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("Expected foo to match")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected scan not to wait for the writer")
+	}
+	ac.mu.Unlock()
+
+	ac.Add("bar")
+	if !ac.Contains("bar") {
+		t.Error("Expected pattern to be visible right after Add returns")
+	}
+}
@@ -22,3 +22,48 @@ func BinarySearch[T cmp.Ordered](arr []T, target T) int {
 
 	return -1 // 未找到目标元素，返回 -1
 }
+
+// SearchFunc 返回 [0, n) 中第一个使 pred 为 true 的下标，pred 必须单调（先 false 后 true），都为 false 时返回 n
+func SearchFunc(n int, pred func(i int) bool) int {
+	left, right := 0, n // 答案在 [left, right] 中
+	for left < right {
+		mid := left + (right-left)/2
+		if pred(mid) {
+			right = mid // mid 满足条件，答案在左半部分（包括 mid）
+		} else {
+			left = mid + 1 // mid 不满足条件，答案在右半部分
+		}
+	}
+	return left
+}
+
+// LowerBound 返回第一个大于等于 target 的下标，不存在时返回 len(arr)
+func LowerBound[T cmp.Ordered](arr []T, target T) int {
+	return SearchFunc(len(arr), func(i int) bool { return arr[i] >= target })
+}
+
+// UpperBound 返回第一个大于 target 的下标，不存在时返回 len(arr)
+func UpperBound[T cmp.Ordered](arr []T, target T) int {
+	return SearchFunc(len(arr), func(i int) bool { return arr[i] > target })
+}
+
+// EqualRange 返回等于 target 的元素所在的区间 [lo, hi)，不存在时 lo == hi
+func EqualRange[T cmp.Ordered](arr []T, target T) (lo, hi int) {
+	return LowerBound(arr, target), UpperBound(arr, target)
+}
+
+// LowerBoundFunc 按比较函数查找第一个不小于 target 的下标，compare 返回负数、0、正数分别表示小于、等于、大于
+func LowerBoundFunc[E, T any](arr []E, target T, compare func(E, T) int) int {
+	return SearchFunc(len(arr), func(i int) bool { return compare(arr[i], target) >= 0 })
+}
+
+// UpperBoundFunc 按比较函数查找第一个大于 target 的下标
+func UpperBoundFunc[E, T any](arr []E, target T, compare func(E, T) int) int {
+	return SearchFunc(len(arr), func(i int) bool { return compare(arr[i], target) > 0 })
+}
+
+// BinarySearchFunc 按比较函数查找 target，返回第一个匹配的下标，未找到时返回应插入的位置和 false
+func BinarySearchFunc[E, T any](arr []E, target T, compare func(E, T) int) (int, bool) {
+	i := LowerBoundFunc(arr, target, compare)
+	return i, i < len(arr) && compare(arr[i], target) == 0
+}
@@ -0,0 +1,60 @@
+package algorithm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBinarySearchBounds(t *testing.T) {
+	arr := []int{1, 2, 2, 2, 5, 7}
+
+	if i := BinarySearch(arr, 5); i != 4 {
+		t.Errorf("Expected 4, got %d", i)
+	}
+	if i := LowerBound(arr, 2); i != 1 {
+		t.Errorf("Expected lower bound 1, got %d", i)
+	}
+	if i := UpperBound(arr, 2); i != 4 {
+		t.Errorf("Expected upper bound 4, got %d", i)
+	}
+	if lo, hi := EqualRange(arr, 3); lo != 4 || hi != 4 {
+		t.Errorf("Expected empty range at 4, got [%d, %d)", lo, hi)
+	}
+	if i := LowerBound(arr, 8); i != len(arr) {
+		t.Errorf("Expected %d, got %d", len(arr), i)
+	}
+	if i := LowerBound([]int{}, 1); i != 0 {
+		t.Errorf("Expected 0 for empty slice, got %d", i)
+	}
+
+	// 求满足 i*i >= 50 的最小 i
+	if i := SearchFunc(100, func(i int) bool { return i*i >= 50 }); i != 8 {
+		t.Errorf("Expected 8, got %d", i)
+	}
+}
+
+func TestBinarySearchFunc(t *testing.T) {
+	type user struct {
+		name  string
+		score int
+	}
+	users := []user{{"a", 10}, {"b", 20}, {"c", 20}, {"d", 30}}
+	byScore := func(u user, score int) int { return u.score - score }
+
+	if i, ok := BinarySearchFunc(users, 20, byScore); !ok || users[i].name != "b" {
+		t.Errorf("Expected to find b, got %d %v", i, ok)
+	}
+	if i, ok := BinarySearchFunc(users, 25, byScore); ok || i != 3 {
+		t.Errorf("Expected insert position 3, got %d %v", i, ok)
+	}
+	if i := UpperBoundFunc(users, 20, byScore); i != 3 {
+		t.Errorf("Expected upper bound 3, got %d", i)
+	}
+
+	names := []string{"apple", "banana", "cherry"}
+	if i, ok := BinarySearchFunc(names, "BANANA", func(s, target string) int {
+		return strings.Compare(s, strings.ToLower(target))
+	}); !ok || i != 1 {
+		t.Errorf("Expected to find banana at 1, got %d %v", i, ok)
+	}
+}
@@ -0,0 +1,94 @@
+package algorithm
+
+import (
+	"sort"
+)
+
+type bkNode struct {
+	word     string
+	children map[int]*bkNode // 按与当前节点的距离索引子节点
+}
+
+// BKMatch BK 树的查询结果
+type BKMatch struct {
+	Word     string
+	Distance int
+}
+
+// BKTree 基于编辑距离的 BK 树，用于查找与查询词距离不超过 k 的所有单词
+// 距离函数必须满足三角不等式，默认使用按 rune 计算的 Levenshtein 距离
+type BKTree struct {
+	root     *bkNode
+	size     int
+	distance func(a, b string) int
+}
+
+func NewBKTree(words ...string) *BKTree {
+	return NewBKTreeFunc(func(a, b string) int { return Levenshtein(a, b) }, words...)
+}
+
+// NewBKTreeFunc 使用自定义距离函数创建 BK 树
+func NewBKTreeFunc(distance func(a, b string) int, words ...string) *BKTree {
+	tree := &BKTree{distance: distance}
+	for _, word := range words {
+		tree.Insert(word)
+	}
+	return tree
+}
+
+// Insert 插入单词，已存在时返回 false
+func (t *BKTree) Insert(word string) bool {
+	if t.root == nil {
+		t.root = &bkNode{word: word, children: make(map[int]*bkNode)}
+		t.size++
+		return true
+	}
+	node := t.root
+	for {
+		d := t.distance(word, node.word)
+		if d == 0 {
+			return false
+		}
+		child, exists := node.children[d]
+		if !exists {
+			node.children[d] = &bkNode{word: word, children: make(map[int]*bkNode)}
+			t.size++
+			return true
+		}
+		node = child
+	}
+}
+
+func (t *BKTree) Len() int {
+	return t.size
+}
+
+// Search 返回与 query 距离不超过 k 的所有单词，按距离从小到大排序，距离相同时按字典序
+func (t *BKTree) Search(query string, k int) []BKMatch {
+	if t.root == nil {
+		return nil
+	}
+	var matches []BKMatch
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d := t.distance(query, node.word)
+		if d <= k {
+			matches = append(matches, BKMatch{Word: node.word, Distance: d})
+		}
+		// 根据三角不等式，只有距离在 [d-k, d+k] 内的子树才可能包含结果
+		for childDist, child := range node.children {
+			if childDist >= d-k && childDist <= d+k {
+				stack = append(stack, child)
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Word < matches[j].Word
+	})
+	return matches
+}
@@ -0,0 +1,31 @@
+package algorithm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBKTree(t *testing.T) {
+	tree := NewBKTree("book", "books", "cake", "boo", "cape", "cart", "boon", "cook")
+	if tree.Insert("book") {
+		t.Error("Expected duplicate insert to return false")
+	}
+	if tree.Len() != 8 {
+		t.Errorf("Expected 8 words, got %d", tree.Len())
+	}
+
+	matches := tree.Search("bo0k", 1)
+	if fmt.Sprint(matches) != "[{book 1}]" {
+		t.Errorf("Unexpected matches %v", matches)
+	}
+
+	matches = tree.Search("book", 1)
+	expected := "[{book 0} {boo 1} {books 1} {boon 1} {cook 1}]"
+	if fmt.Sprint(matches) != expected {
+		t.Errorf("Expected %s, got %v", expected, matches)
+	}
+
+	if matches := NewBKTree().Search("book", 2); len(matches) != 0 {
+		t.Errorf("Expected no matches in an empty tree, got %v", matches)
+	}
+}
@@ -1,22 +1,23 @@
 package algorithm
 
 import (
-	"cmp"
+	"iter"
 )
 
-type DoubleListNode[T cmp.Ordered] struct {
+type DoubleListNode[T any] struct {
 	Value T
 	Prev  *DoubleListNode[T]
 	Next  *DoubleListNode[T]
+	list  *DoubleList[T] // 节点所属的链表，移除后为 nil
 }
 
-type DoubleList[T cmp.Ordered] struct {
+type DoubleList[T any] struct {
 	Head *DoubleListNode[T]
 	Tail *DoubleListNode[T]
 	Size int
 }
 
-func NewDoubleList[T cmp.Ordered]() *DoubleList[T] {
+func NewDoubleList[T any]() *DoubleList[T] {
 	return &DoubleList[T]{
 		Head: nil,
 		Tail: nil,
@@ -24,59 +25,40 @@ func NewDoubleList[T cmp.Ordered]() *DoubleList[T] {
 	}
 }
 
-func (dl *DoubleList[T]) Append(value T) {
+// Append 在尾部追加元素并返回新节点
+func (dl *DoubleList[T]) Append(value T) *DoubleListNode[T] {
 	node := &DoubleListNode[T]{Value: value}
-	if dl.Head == nil {
-		dl.Head = node
-		dl.Tail = node
-	} else {
-		dl.Tail.Next = node
-		node.Prev = dl.Tail
-		dl.Tail = node
-	}
-	dl.Size++
+	dl.linkBack(node)
+	return node
 }
 
-func (dl *DoubleList[T]) Prepend(value T) {
+// Prepend 在头部插入元素并返回新节点
+func (dl *DoubleList[T]) Prepend(value T) *DoubleListNode[T] {
 	node := &DoubleListNode[T]{Value: value}
-	if dl.Head == nil {
-		dl.Head = node
-		dl.Tail = node
-	} else {
-		node.Next = dl.Head
-		dl.Head.Prev = node
-		dl.Head = node
-	}
-	dl.Size++
+	dl.linkFront(node)
+	return node
 }
 
 func (dl *DoubleList[T]) Remove(node *DoubleListNode[T]) {
-	if node == nil || dl.Head == nil {
+	if node == nil || node.list != dl {
 		return
 	}
-
-	if node.Prev != nil {
-		node.Prev.Next = node.Next
-	} else {
-		dl.Head = node.Next // Node is head
-	}
-
-	if node.Next != nil {
-		node.Next.Prev = node.Prev
-	} else {
-		dl.Tail = node.Prev // Node is tail
-	}
-
-	dl.Size--
-	node.Prev = nil
-	node.Next = nil
+	dl.unlink(node)
 	node.Value = *new(T) // 避免继续引用另一个 值
 }
 
-func (dl *DoubleList[T]) Find(value T) *DoubleListNode[T] {
+// Find 查找 dl 中第一个等于 value 的节点，T 不可比较时使用 FindFunc
+func Find[T comparable](dl *DoubleList[T], value T) *DoubleListNode[T] {
+	return dl.FindFunc(func(v T) bool {
+		return v == value
+	})
+}
+
+// FindFunc 查找第一个满足 pred 的节点
+func (dl *DoubleList[T]) FindFunc(pred func(value T) bool) *DoubleListNode[T] {
 	current := dl.Head
 	for current != nil {
-		if current.Value == value {
+		if pred(current.Value) {
 			return current
 		}
 		current = current.Next
@@ -89,6 +71,7 @@ func (dl *DoubleList[T]) Clear() {
 		next := current.Next
 		current.Prev = nil
 		current.Next = nil
+		current.list = nil
 		current.Value = *new(T) // 避免继续引用另一个 值
 		current = next
 	}
@@ -106,36 +89,201 @@ func (dl *DoubleList[T]) Length() int {
 }
 
 func (dl *DoubleList[T]) InsertAfter(node *DoubleListNode[T], value T) {
-	if node == nil {
+	if node == nil || node.list != dl {
 		return
 	}
-	newNode := &DoubleListNode[T]{Value: value}
-	newNode.Prev = node
-	newNode.Next = node.Next
+	dl.linkAfter(&DoubleListNode[T]{Value: value}, node)
+}
 
-	if node.Next != nil {
-		node.Next.Prev = newNode
+func (dl *DoubleList[T]) InsertBefore(node *DoubleListNode[T], value T) {
+	if node == nil || node.list != dl {
+		return
+	}
+	dl.linkBefore(&DoubleListNode[T]{Value: value}, node)
+}
+
+// PopFront 移除并返回头部元素，链表为空时返回 false
+func (dl *DoubleList[T]) PopFront() (T, bool) {
+	if dl.Head == nil {
+		return *new(T), false
+	}
+	node := dl.Head
+	dl.unlink(node)
+	return node.Value, true
+}
+
+// PopBack 移除并返回尾部元素，链表为空时返回 false
+func (dl *DoubleList[T]) PopBack() (T, bool) {
+	if dl.Tail == nil {
+		return *new(T), false
+	}
+	node := dl.Tail
+	dl.unlink(node)
+	return node.Value, true
+}
+
+// MoveToFront 把节点移动到头部
+func (dl *DoubleList[T]) MoveToFront(node *DoubleListNode[T]) {
+	if node == nil || node.list != dl || dl.Head == node {
+		return
+	}
+	dl.unlink(node)
+	dl.linkFront(node)
+}
+
+// MoveToBack 把节点移动到尾部
+func (dl *DoubleList[T]) MoveToBack(node *DoubleListNode[T]) {
+	if node == nil || node.list != dl || dl.Tail == node {
+		return
+	}
+	dl.unlink(node)
+	dl.linkBack(node)
+}
+
+// MoveAfter 把节点移动到 mark 之后
+func (dl *DoubleList[T]) MoveAfter(node, mark *DoubleListNode[T]) {
+	if node == nil || mark == nil || node == mark || node.list != dl || mark.list != dl {
+		return
+	}
+	dl.unlink(node)
+	dl.linkAfter(node, mark)
+}
+
+// MoveBefore 把节点移动到 mark 之前
+func (dl *DoubleList[T]) MoveBefore(node, mark *DoubleListNode[T]) {
+	if node == nil || mark == nil || node == mark || node.list != dl || mark.list != dl {
+		return
+	}
+	dl.unlink(node)
+	dl.linkBefore(node, mark)
+}
+
+// Splice 把 other 的所有节点按顺序移动到当前链表尾部，other 随后为空，节点指针保持有效
+func (dl *DoubleList[T]) Splice(other *DoubleList[T]) {
+	if other == nil || other == dl || other.Head == nil {
+		return
+	}
+	for node := other.Head; node != nil; node = node.Next {
+		node.list = dl
+	}
+	if dl.Tail == nil {
+		dl.Head = other.Head
 	} else {
-		dl.Tail = newNode // Node is tail
+		dl.Tail.Next = other.Head
+		other.Head.Prev = dl.Tail
 	}
+	dl.Tail = other.Tail
+	dl.Size += other.Size
+	other.Head, other.Tail, other.Size = nil, nil, 0
+}
 
-	node.Next = newNode
+// All 从头到尾遍历元素
+func (dl *DoubleList[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for node := dl.Head; node != nil; {
+			next := node.Next // 允许在遍历时删除当前节点
+			if !yield(node.Value) {
+				return
+			}
+			node = next
+		}
+	}
+}
+
+// Backward 从尾到头遍历元素
+func (dl *DoubleList[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for node := dl.Tail; node != nil; {
+			prev := node.Prev
+			if !yield(node.Value) {
+				return
+			}
+			node = prev
+		}
+	}
+}
+
+// Nodes 从头到尾遍历节点，遍历过程中可以删除或移动当前节点
+func (dl *DoubleList[T]) Nodes() iter.Seq[*DoubleListNode[T]] {
+	return func(yield func(*DoubleListNode[T]) bool) {
+		for node := dl.Head; node != nil; {
+			next := node.Next
+			if !yield(node) {
+				return
+			}
+			node = next
+		}
+	}
+}
+
+func (dl *DoubleList[T]) linkFront(node *DoubleListNode[T]) {
+	node.list = dl
+	node.Prev = nil
+	node.Next = dl.Head
+	if dl.Head == nil {
+		dl.Tail = node
+	} else {
+		dl.Head.Prev = node
+	}
+	dl.Head = node
 	dl.Size++
 }
-func (dl *DoubleList[T]) InsertBefore(node *DoubleListNode[T], value T) {
-	if node == nil {
-		return
+
+func (dl *DoubleList[T]) linkBack(node *DoubleListNode[T]) {
+	node.list = dl
+	node.Next = nil
+	node.Prev = dl.Tail
+	if dl.Tail == nil {
+		dl.Head = node
+	} else {
+		dl.Tail.Next = node
 	}
-	newNode := &DoubleListNode[T]{Value: value}
-	newNode.Next = node
-	newNode.Prev = node.Prev
+	dl.Tail = node
+	dl.Size++
+}
 
-	if node.Prev != nil {
-		node.Prev.Next = newNode
+func (dl *DoubleList[T]) linkAfter(node, mark *DoubleListNode[T]) {
+	node.list = dl
+	node.Prev = mark
+	node.Next = mark.Next
+	if mark.Next != nil {
+		mark.Next.Prev = node
 	} else {
-		dl.Head = newNode // Node is head
+		dl.Tail = node // Node is tail
 	}
+	mark.Next = node
+	dl.Size++
+}
 
-	node.Prev = newNode
+func (dl *DoubleList[T]) linkBefore(node, mark *DoubleListNode[T]) {
+	node.list = dl
+	node.Next = mark
+	node.Prev = mark.Prev
+	if mark.Prev != nil {
+		mark.Prev.Next = node
+	} else {
+		dl.Head = node // Node is head
+	}
+	mark.Prev = node
 	dl.Size++
 }
+
+// unlink 把节点从链表中摘下，保留节点的值
+func (dl *DoubleList[T]) unlink(node *DoubleListNode[T]) {
+	if node.Prev != nil {
+		node.Prev.Next = node.Next
+	} else {
+		dl.Head = node.Next // Node is head
+	}
+
+	if node.Next != nil {
+		node.Next.Prev = node.Prev
+	} else {
+		dl.Tail = node.Prev // Node is tail
+	}
+
+	dl.Size--
+	node.Prev = nil
+	node.Next = nil
+	node.list = nil
+}
@@ -0,0 +1,104 @@
+package algorithm
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+type listItem struct {
+	id   int
+	tags []string // 含切片，不可比较
+}
+
+func TestDoubleList_AnyType(t *testing.T) {
+	dl := NewDoubleList[listItem]()
+	dl.Append(listItem{id: 1})
+	dl.Append(listItem{id: 2, tags: []string{"x"}})
+	dl.Prepend(listItem{id: 0})
+
+	node := dl.FindFunc(func(v listItem) bool { return v.id == 2 })
+	if node == nil || node.Value.tags[0] != "x" {
+		t.Fatal("Expected to find item 2")
+	}
+	if dl.FindFunc(func(v listItem) bool { return v.id == 9 }) != nil {
+		t.Error("Expected item 9 to be missing")
+	}
+	if v, ok := dl.PopFront(); !ok || v.id != 0 {
+		t.Errorf("Expected to pop item 0, got %v", v)
+	}
+	if v, ok := dl.PopBack(); !ok || v.id != 2 {
+		t.Errorf("Expected to pop item 2, got %v", v)
+	}
+	dl.PopBack()
+	if _, ok := dl.PopBack(); ok || !dl.IsEmpty() {
+		t.Error("Expected empty list")
+	}
+}
+
+func TestDoubleList_Move(t *testing.T) {
+	dl := NewDoubleList[int]()
+	n1 := dl.Append(1)
+	n2 := dl.Append(2)
+	n3 := dl.Append(3)
+
+	dl.MoveToFront(n3)
+	if got := fmt.Sprint(slices.Collect(dl.All())); got != "[3 1 2]" {
+		t.Errorf("Unexpected order after MoveToFront %s", got)
+	}
+	dl.MoveToBack(n3)
+	dl.MoveAfter(n1, n2)
+	if got := fmt.Sprint(slices.Collect(dl.All())); got != "[2 1 3]" {
+		t.Errorf("Unexpected order after MoveAfter %s", got)
+	}
+	dl.MoveBefore(n3, n2)
+	if got := fmt.Sprint(slices.Collect(dl.Backward())); got != "[1 2 3]" {
+		t.Errorf("Unexpected backward order %s", got)
+	}
+	if Find(dl, 2) != n2 {
+		t.Error("Expected Find to return node 2")
+	}
+
+	// 其他链表的节点不应影响当前链表
+	other := NewDoubleList[int]()
+	foreign := other.Append(9)
+	dl.MoveToFront(foreign)
+	dl.Remove(foreign)
+	if dl.Length() != 3 || other.Length() != 1 {
+		t.Errorf("Foreign node must be ignored, got sizes %d and %d", dl.Length(), other.Length())
+	}
+}
+
+func TestDoubleList_Splice(t *testing.T) {
+	a := NewDoubleList[int]()
+	a.Append(1)
+	b := NewDoubleList[int]()
+	n2 := b.Append(2)
+	b.Append(3)
+
+	a.Splice(b)
+	if got := fmt.Sprint(slices.Collect(a.All())); got != "[1 2 3]" || a.Length() != 3 {
+		t.Errorf("Unexpected list after splice %s", got)
+	}
+	if !b.IsEmpty() || b.Head != nil {
+		t.Error("Expected spliced list to be empty")
+	}
+
+	// 节点已经属于 a
+	a.MoveToFront(n2)
+	if a.Head != n2 {
+		t.Error("Expected spliced node to be movable in the new list")
+	}
+
+	for v := range a.All() {
+		if v == 1 {
+			break
+		}
+	}
+	for node := range a.Nodes() {
+		a.Remove(node)
+	}
+	if !a.IsEmpty() {
+		t.Error("Expected all nodes to be removed during iteration")
+	}
+}
@@ -1,8 +1,9 @@
 package algorithm
 
-// 最小编辑距离  s1--> s2
+// 最小编辑距离  s1--> s2，按 rune 比较
 func EditDistance(s1, s2 string) int {
-	m, n := len(s1), len(s2)
+	r1, r2 := []rune(s1), []rune(s2)
+	m, n := len(r1), len(r2)
 	if m == 0 {
 		return n // 如果 s1 为空，返回 s2 的长度
 	}
@@ -11,37 +12,37 @@ func EditDistance(s1, s2 string) int {
 	}
 
 	// 创建二维 DP 数组
-	dp := make([][]int, m) // dp[i][j] 表示 s1 的前 i 个字符转换为 s2 的前 j 个字符所需的最小操作数
+	dp := make([][]int, m+1) // dp[i][j] 表示 s1 的前 i 个字符转换为 s2 的前 j 个字符所需的最小操作数
 	for i := range dp {
-		dp[i] = make([]int, n)
+		dp[i] = make([]int, n+1)
 	}
 
 	// 初始化第一行和第一列
-	for i := 0; i < m; i++ { // dp[i][0] 表示将 s1 的前 i 个字符转换为空字符串所需的操作数
+	for i := 0; i <= m; i++ { // dp[i][0] 表示将 s1 的前 i 个字符转换为空字符串所需的操作数
 		dp[i][0] = i // 删除操作
 	}
-	for j := 0; j < n; j++ { // dp[0][j] 表示将空字符串转换为 s2 的前 j 个字符所需的操作数
+	for j := 0; j <= n; j++ { // dp[0][j] 表示将空字符串转换为 s2 的前 j 个字符所需的操作数
 		dp[0][j] = j // 插入操作
 	}
 
 	// 填充 DP 数组
-	for i := 1; i < m; i++ {
-		for j := 1; j < n; j++ {
-			if s1[i] == s2[j] {
+	for i := 1; i <= m; i++ {
+		for j := 1; j <= n; j++ {
+			if r1[i-1] == r2[j-1] {
 				dp[i][j] = dp[i-1][j-1] // 如果字符相同，不需要操作
 			} else {
-				// 只需要当前行和前一行的值
 				// 取删除、插入和替换操作的最小值
 				dp[i][j] = min(dp[i][j-1]+1, dp[i-1][j]+1, dp[i-1][j-1]+1)
 			}
 		}
 	}
 
-	return dp[m-1][n-1] // 返回将 s1 转换为 s2 所需的最小操作数
+	return dp[m][n] // 返回将 s1 转换为 s2 所需的最小操作数
 }
 
 func EditDistanceWithButtomUp(s1, s2 string) int {
-	m, n := len(s1), len(s2)
+	r1, r2 := []rune(s1), []rune(s2)
+	m, n := len(r1), len(r2)
 	if m == 0 {
 		return n // 如果 s1 为空，返回 s2 的长度
 	}
@@ -57,7 +58,7 @@ func EditDistanceWithButtomUp(s1, s2 string) int {
 	for i := 1; i <= m; i++ {
 		currentRow[0] = i // 初始化当前行的第一列
 		for j := 1; j <= n; j++ {
-			if s1[i-1] == s2[j-1] {
+			if r1[i-1] == r2[j-1] {
 				currentRow[j] = prevRow[j-1] // 如果字符相同，不需要操作
 			} else {
 				// 取删除、插入和替换操作的最小值
@@ -69,3 +70,96 @@ func EditDistanceWithButtomUp(s1, s2 string) int {
 	}
 	return prevRow[n] // 返回将 s1 转换为 s2 所需的最小操作数
 }
+
+type editConfig struct {
+	insertCost     int
+	deleteCost     int
+	substituteCost int
+	transposeCost  int
+	damerau        bool
+	maxDistance    int // < 0 表示不限制
+}
+
+type EditOption func(*editConfig)
+
+// WithEditCosts 设置插入、删除和替换的代价，默认均为 1
+func WithEditCosts(insert, del, substitute int) EditOption {
+	return func(c *editConfig) {
+		c.insertCost = insert
+		c.deleteCost = del
+		c.substituteCost = substitute
+	}
+}
+
+// WithTransposition 允许交换相邻的两个字符（Damerau 距离的 OSA 版本），cost 为一次交换的代价
+func WithTransposition(cost int) EditOption {
+	return func(c *editConfig) {
+		c.damerau = true
+		c.transposeCost = cost
+	}
+}
+
+// WithMaxDistance 距离超过 limit 时提前结束计算，此时返回 limit+1
+func WithMaxDistance(limit int) EditOption {
+	return func(c *editConfig) {
+		c.maxDistance = limit
+	}
+}
+
+// Levenshtein 计算 s1 到 s2 的编辑距离，按 rune 比较，支持自定义代价、相邻交换和提前结束
+func Levenshtein(s1, s2 string, opts ...EditOption) int {
+	cfg := &editConfig{insertCost: 1, deleteCost: 1, substituteCost: 1, transposeCost: 1, maxDistance: -1}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	r1, r2 := []rune(s1), []rune(s2)
+	m, n := len(r1), len(r2)
+	limited := cfg.maxDistance >= 0
+
+	// 长度差带来的最小代价已经超过上限，无需计算
+	if limited {
+		var lower int
+		if m > n {
+			lower = (m - n) * cfg.deleteCost
+		} else {
+			lower = (n - m) * cfg.insertCost
+		}
+		if lower > cfg.maxDistance {
+			return cfg.maxDistance + 1
+		}
+	}
+
+	// 只保留三行：前两行用于相邻交换，prevRow 和 currentRow 用于常规转移
+	prevPrevRow := make([]int, n+1)
+	prevRow := make([]int, n+1)
+	currentRow := make([]int, n+1)
+	for j := 0; j <= n; j++ {
+		prevRow[j] = j * cfg.insertCost
+	}
+	prevRowMin := 0
+	for i := 1; i <= m; i++ {
+		currentRow[0] = i * cfg.deleteCost
+		rowMin := currentRow[0]
+		for j := 1; j <= n; j++ {
+			substitute := prevRow[j-1]
+			if r1[i-1] != r2[j-1] {
+				substitute += cfg.substituteCost
+			}
+			currentRow[j] = min(currentRow[j-1]+cfg.insertCost, prevRow[j]+cfg.deleteCost, substitute)
+			if cfg.damerau && i > 1 && j > 1 && r1[i-1] == r2[j-2] && r1[i-2] == r2[j-1] && r1[i-1] != r2[j-1] {
+				currentRow[j] = min(currentRow[j], prevPrevRow[j-2]+cfg.transposeCost)
+			}
+			rowMin = min(rowMin, currentRow[j])
+		}
+		// 整行的最小值都超过上限，最终结果不可能更小；相邻交换会跨两行转移，需同时检查上一行
+		if limited && rowMin > cfg.maxDistance && (!cfg.damerau || prevRowMin > cfg.maxDistance) {
+			return cfg.maxDistance + 1
+		}
+		prevRowMin = rowMin
+		prevPrevRow, prevRow, currentRow = prevRow, currentRow, prevPrevRow
+	}
+	if limited && prevRow[n] > cfg.maxDistance {
+		return cfg.maxDistance + 1
+	}
+	return prevRow[n]
+}
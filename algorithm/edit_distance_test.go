@@ -0,0 +1,60 @@
+package algorithm
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		s1, s2 string
+		want   int
+	}{
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"horse", "ros", 3},
+		{"a", "b", 1},
+		{"ab", "b", 1},
+		{"中国人", "中华人", 1},
+		{"你好", "你好吗", 1},
+	}
+	for _, c := range cases {
+		if got := EditDistance(c.s1, c.s2); got != c.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", c.s1, c.s2, got, c.want)
+		}
+		if got := EditDistanceWithButtomUp(c.s1, c.s2); got != c.want {
+			t.Errorf("EditDistanceWithButtomUp(%q, %q) = %d, want %d", c.s1, c.s2, got, c.want)
+		}
+		if got := Levenshtein(c.s1, c.s2); got != c.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", c.s1, c.s2, got, c.want)
+		}
+	}
+}
+
+func TestLevenshteinOptions(t *testing.T) {
+	if got := Levenshtein("ab", "ba"); got != 2 {
+		t.Errorf("Expected 2 without transposition, got %d", got)
+	}
+	if got := Levenshtein("ab", "ba", WithTransposition(1)); got != 1 {
+		t.Errorf("Expected 1 with transposition, got %d", got)
+	}
+	if got := Levenshtein("abcd", "acbd", WithTransposition(1)); got != 1 {
+		t.Errorf("Expected 1 with transposition, got %d", got)
+	}
+
+	// 替换代价大于插入加删除时，应改用插入和删除
+	if got := Levenshtein("a", "b", WithEditCosts(1, 1, 5)); got != 2 {
+		t.Errorf("Expected 2 with expensive substitution, got %d", got)
+	}
+	if got := Levenshtein("abc", "abcde", WithEditCosts(3, 1, 1)); got != 6 {
+		t.Errorf("Expected 6 with insert cost 3, got %d", got)
+	}
+
+	if got := Levenshtein("kitten", "sitting", WithMaxDistance(2)); got != 3 {
+		t.Errorf("Expected max+1 when exceeding the limit, got %d", got)
+	}
+	if got := Levenshtein("kitten", "sitting", WithMaxDistance(3)); got != 3 {
+		t.Errorf("Expected 3 within the limit, got %d", got)
+	}
+	if got := Levenshtein("a", "abcdefgh", WithMaxDistance(1)); got != 2 {
+		t.Errorf("Expected max+1 for large length difference, got %d", got)
+	}
+}
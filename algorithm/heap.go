@@ -2,6 +2,7 @@ package algorithm
 
 import (
 	"cmp"
+	"sort"
 )
 
 type MinHeap[T cmp.Ordered] struct {
@@ -51,6 +52,40 @@ func (h *MinHeap[T]) adjustUp(index int) { // 向上调整堆
 	}
 }
 
+func (h *MinHeap[T]) Push(val T) {
+	h.heap = append(h.heap, val)
+	h.adjustUp(len(h.heap) - 1)
+}
+
+// Pop 弹出最小值，堆为空时返回 false
+func (h *MinHeap[T]) Pop() (T, bool) {
+	var zero T
+	n := len(h.heap)
+	if n == 0 {
+		return zero, false
+	}
+	top := h.heap[0]
+	h.heap[0] = h.heap[n-1]
+	h.heap = h.heap[:n-1]
+	if len(h.heap) > 0 {
+		h.adjustDown(0)
+	}
+	return top, true
+}
+
+// Peek 查看最小值但不弹出
+func (h *MinHeap[T]) Peek() (T, bool) {
+	if len(h.heap) == 0 {
+		var zero T
+		return zero, false
+	}
+	return h.heap[0], true
+}
+
+func (h *MinHeap[T]) Len() int {
+	return len(h.heap)
+}
+
 //=====================优先队列=====================
 
 type Item[T cmp.Ordered] struct {
@@ -87,3 +122,196 @@ func (pq *PriorityQueueInt) Pop() any {
 	*pq = old[0 : n-1]
 	return item
 }
+
+// PQItem 优先队列中的元素，Push 返回的指针可用于 Update 和 Remove
+type PQItem[T any] struct {
+	Value    T
+	Priority int
+	index    int // 在堆中的索引，-1 表示已不在队列中
+}
+
+// PriorityQueue 支持修改优先级和删除任意元素的优先队列，各操作均为 O(log n)
+type PriorityQueue[T any] struct {
+	items []*PQItem[T]
+	less  func(a, b *PQItem[T]) bool
+}
+
+// NewPriorityQueue 创建优先级小的先出队的优先队列
+func NewPriorityQueue[T any]() *PriorityQueue[T] {
+	return NewPriorityQueueFunc(func(a, b *PQItem[T]) bool {
+		return a.Priority < b.Priority
+	})
+}
+
+// NewMaxPriorityQueue 创建优先级大的先出队的优先队列
+func NewMaxPriorityQueue[T any]() *PriorityQueue[T] {
+	return NewPriorityQueueFunc(func(a, b *PQItem[T]) bool {
+		return a.Priority > b.Priority
+	})
+}
+
+// NewPriorityQueueFunc 使用自定义比较函数创建优先队列，less(a, b) 为 true 时 a 先出队
+func NewPriorityQueueFunc[T any](less func(a, b *PQItem[T]) bool) *PriorityQueue[T] {
+	return &PriorityQueue[T]{less: less}
+}
+
+func (pq *PriorityQueue[T]) Len() int {
+	return len(pq.items)
+}
+
+func (pq *PriorityQueue[T]) Push(value T, priority int) *PQItem[T] {
+	item := &PQItem[T]{Value: value, Priority: priority, index: len(pq.items)}
+	pq.items = append(pq.items, item)
+	pq.adjustUp(item.index)
+	return item
+}
+
+// Pop 弹出队首元素，队列为空时返回 false
+func (pq *PriorityQueue[T]) Pop() (*PQItem[T], bool) {
+	if len(pq.items) == 0 {
+		return nil, false
+	}
+	item := pq.items[0]
+	pq.removeAt(0)
+	return item, true
+}
+
+// Peek 查看队首元素但不弹出
+func (pq *PriorityQueue[T]) Peek() (*PQItem[T], bool) {
+	if len(pq.items) == 0 {
+		return nil, false
+	}
+	return pq.items[0], true
+}
+
+// Update 修改元素的优先级并调整位置，元素不在队列中时返回 false
+func (pq *PriorityQueue[T]) Update(item *PQItem[T], priority int) bool {
+	if !pq.contains(item) {
+		return false
+	}
+	item.Priority = priority
+	pq.fix(item.index)
+	return true
+}
+
+// Remove 删除任意元素，元素不在队列中时返回 false
+func (pq *PriorityQueue[T]) Remove(item *PQItem[T]) bool {
+	if !pq.contains(item) {
+		return false
+	}
+	pq.removeAt(item.index)
+	return true
+}
+
+func (pq *PriorityQueue[T]) contains(item *PQItem[T]) bool {
+	return item != nil && item.index >= 0 && item.index < len(pq.items) && pq.items[item.index] == item
+}
+
+func (pq *PriorityQueue[T]) removeAt(index int) {
+	n := len(pq.items) - 1
+	item := pq.items[index]
+	if index != n {
+		pq.swap(index, n)
+	}
+	pq.items[n] = nil // 避免内存泄漏
+	pq.items = pq.items[:n]
+	item.index = -1
+	if index != n {
+		pq.fix(index)
+	}
+}
+
+func (pq *PriorityQueue[T]) fix(index int) {
+	if !pq.adjustDown(index) {
+		pq.adjustUp(index)
+	}
+}
+
+func (pq *PriorityQueue[T]) swap(i, j int) {
+	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
+	pq.items[i].index = i
+	pq.items[j].index = j
+}
+
+func (pq *PriorityQueue[T]) adjustUp(index int) {
+	for index > 0 {
+		parent := (index - 1) / 2
+		if !pq.less(pq.items[index], pq.items[parent]) {
+			break
+		}
+		pq.swap(index, parent)
+		index = parent
+	}
+}
+
+// adjustDown 向下调整，返回节点是否发生了移动
+func (pq *PriorityQueue[T]) adjustDown(index int) bool {
+	n := len(pq.items)
+	start := index
+	for {
+		smallest := index
+		left, right := 2*index+1, 2*index+2
+		if left < n && pq.less(pq.items[left], pq.items[smallest]) {
+			smallest = left
+		}
+		if right < n && pq.less(pq.items[right], pq.items[smallest]) {
+			smallest = right
+		}
+		if smallest == index {
+			break
+		}
+		pq.swap(index, smallest)
+		index = smallest
+	}
+	return index != start
+}
+
+//=====================TopK=====================
+
+// TopK 在数据流中维护最大的 k 个元素，内部是大小为 k 的最小堆
+type TopK[T any] struct {
+	k    int
+	less func(a, b T) bool
+	pq   *PriorityQueue[T]
+}
+
+// NewTopK 创建 TopK，less(a, b) 为 true 表示 a 小于 b
+func NewTopK[T any](k int, less func(a, b T) bool) *TopK[T] {
+	return &TopK[T]{
+		k:    k,
+		less: less,
+		pq: NewPriorityQueueFunc(func(a, b *PQItem[T]) bool {
+			return less(a.Value, b.Value)
+		}),
+	}
+}
+
+// Add 加入一个元素，只有比当前第 k 大的元素更大时才会保留
+func (t *TopK[T]) Add(value T) {
+	if t.k <= 0 {
+		return
+	}
+	if t.pq.Len() < t.k {
+		t.pq.Push(value, 0)
+		return
+	}
+	top, _ := t.pq.Peek()
+	if t.less(top.Value, value) {
+		top.Value = value
+		t.pq.fix(0)
+	}
+}
+
+func (t *TopK[T]) Len() int {
+	return t.pq.Len()
+}
+
+// Items 按从大到小的顺序返回当前的 k 个元素
+func (t *TopK[T]) Items() []T {
+	items := make([]T, len(t.pq.items))
+	for i, item := range t.pq.items {
+		items[i] = item.Value
+	}
+	sort.Slice(items, func(i, j int) bool { return t.less(items[j], items[i]) })
+	return items
+}
@@ -20,3 +20,79 @@ func TestContainerHeap(t *testing.T) {
 	}
 
 }
+
+func TestMinHeap(t *testing.T) {
+	h := NewMinHeap[int]()
+	for _, v := range []int{5, 3, 8, 1, 9, 2} {
+		h.Push(v)
+	}
+	if top, ok := h.Peek(); !ok || top != 1 {
+		t.Errorf("Expected peek 1, got %d", top)
+	}
+	expected := []int{1, 2, 3, 5, 8, 9}
+	for _, want := range expected {
+		if got, ok := h.Pop(); !ok || got != want {
+			t.Errorf("Expected %d, got %d", want, got)
+		}
+	}
+	if _, ok := h.Pop(); ok {
+		t.Error("Expected empty heap")
+	}
+}
+
+func TestPriorityQueue(t *testing.T) {
+	pq := NewPriorityQueue[string]()
+	a := pq.Push("a", 5)
+	b := pq.Push("b", 3)
+	c := pq.Push("c", 7)
+	pq.Push("d", 1)
+
+	// decrease-key
+	if !pq.Update(c, 0) {
+		t.Fatal("Expected update to succeed")
+	}
+	if top, _ := pq.Peek(); top.Value != "c" {
+		t.Errorf("Expected c at top, got %s", top.Value)
+	}
+	if !pq.Remove(b) || pq.Remove(b) {
+		t.Error("Expected b to be removed exactly once")
+	}
+	pq.Update(a, 10)
+
+	var order []string
+	for pq.Len() > 0 {
+		item, _ := pq.Pop()
+		order = append(order, item.Value)
+	}
+	if fmt.Sprint(order) != "[c d a]" {
+		t.Errorf("Unexpected order %v", order)
+	}
+	if pq.Update(a, 1) {
+		t.Error("Expected update of popped item to fail")
+	}
+}
+
+func TestMaxPriorityQueue(t *testing.T) {
+	pq := NewMaxPriorityQueue[int]()
+	for i := 0; i < 10; i++ {
+		pq.Push(i, i*7%10)
+	}
+	prev := 1 << 30
+	for pq.Len() > 0 {
+		item, _ := pq.Pop()
+		if item.Priority > prev {
+			t.Fatalf("Expected descending priority, got %d after %d", item.Priority, prev)
+		}
+		prev = item.Priority
+	}
+}
+
+func TestTopK(t *testing.T) {
+	topK := NewTopK(3, func(a, b int) bool { return a < b })
+	for _, v := range []int{4, 1, 9, 7, 3, 8, 2} {
+		topK.Add(v)
+	}
+	if got := fmt.Sprint(topK.Items()); got != "[9 8 7]" {
+		t.Errorf("Expected [9 8 7], got %s", got)
+	}
+}
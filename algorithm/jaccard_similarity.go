@@ -2,7 +2,7 @@ package algorithm
 
 import (
 	"cmp"
-	"sort"
+	"slices"
 )
 
 func JaccardSimilarity[T cmp.Ordered](setA, setB []T) float64 {
@@ -36,6 +36,7 @@ func JaccardSimilarity[T cmp.Ordered](setA, setB []T) float64 {
 	return float64(intersectionCount) / float64(unionCount)
 }
 
+// JaccardSimilarityBySorted 排序后双指针求交集，不会修改调用方的切片，重复元素只计一次
 func JaccardSimilarityBySorted[T cmp.Ordered](setA, setB []T) float64 {
 	if len(setA) == 0 && len(setB) == 0 {
 		return 1.0 // 两个空集合的相似度为 1
@@ -45,8 +46,8 @@ func JaccardSimilarityBySorted[T cmp.Ordered](setA, setB []T) float64 {
 		return 0.0 // 一个空集合和非空集合的相似度为 0
 	}
 
-	sort.Slice(setA, func(i, j int) bool { return setA[i] < setA[j] })
-	sort.Slice(setB, func(i, j int) bool { return setB[i] < setB[j] })
+	setA = sortedUnique(setA)
+	setB = sortedUnique(setB)
 
 	var intersectionCount int
 	for i, j := 0, 0; i < len(setA) && j < len(setB); {
@@ -63,3 +64,10 @@ func JaccardSimilarityBySorted[T cmp.Ordered](setA, setB []T) float64 {
 	unionCount := len(setA) + len(setB) - intersectionCount
 	return float64(intersectionCount) / float64(unionCount)
 }
+
+// sortedUnique 返回排序去重后的副本
+func sortedUnique[T cmp.Ordered](set []T) []T {
+	sorted := slices.Clone(set)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
@@ -1,7 +1,8 @@
 package algorithm
 
 import (
-	"container/list"
+	"sync"
+	"sync/atomic"
 )
 
 type cacheNode[K comparable, V any] struct {
@@ -9,55 +10,172 @@ type cacheNode[K comparable, V any] struct {
 	value V
 }
 
+// CacheStats 缓存命中统计，可直接导出到监控系统
+type CacheStats struct {
+	Hits      uint64 // 命中次数
+	Misses    uint64 // 未命中次数
+	Evictions uint64 // 因容量不足被淘汰的次数
+}
+
+type cacheCounter struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+func (c *cacheCounter) snapshot() CacheStats {
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
 type LRUCache[K comparable, V any] struct {
 	capacity int
-	cache    map[K]*cacheNode[K, V]
-	list     *list.List
+	cache    map[K]*DoubleListNode[cacheNode[K, V]] // 直接保存链表节点，O(1) 定位
+	list     *DoubleList[cacheNode[K, V]]           // 头部为最近使用，尾部为最久未使用
+	onEvict  func(key K, value V)
+	counter  cacheCounter
+	mu       sync.Mutex
+	safe     bool // 是否加锁，NewSyncLRUCache 创建的缓存为 true
 }
 
 func NewLRUCache[K comparable, V any](capacity int) *LRUCache[K, V] {
 	return &LRUCache[K, V]{
 		capacity: capacity,
-		cache:    make(map[K]*cacheNode[K, V]),
-		list:     list.New(),
+		cache:    make(map[K]*DoubleListNode[cacheNode[K, V]]),
+		list:     NewDoubleList[cacheNode[K, V]](),
 	}
 }
+
+// NewSyncLRUCache 创建并发安全的 LRU 缓存，可在多个 goroutine 间共享
+func NewSyncLRUCache[K comparable, V any](capacity int) *LRUCache[K, V] {
+	lru := NewLRUCache[K, V](capacity)
+	lru.safe = true
+	return lru
+}
+
+func (lru *LRUCache[K, V]) lock() {
+	if lru.safe {
+		lru.mu.Lock()
+	}
+}
+
+func (lru *LRUCache[K, V]) unlock() {
+	if lru.safe {
+		lru.mu.Unlock()
+	}
+}
+
+// OnEvict 设置淘汰回调，仅在因容量不足被淘汰时触发，Delete 不会触发
+// 回调在释放锁之后执行，可以在回调中再次访问缓存
+func (lru *LRUCache[K, V]) OnEvict(fn func(key K, value V)) {
+	lru.lock()
+	defer lru.unlock()
+	lru.onEvict = fn
+}
+
 func (lru *LRUCache[K, V]) Get(key K) (V, bool) {
-	if node, exists := lru.cache[key]; exists {
-		// 移动到列表头部，表示最近使用
-		for e := lru.list.Front(); e != nil; e = e.Next() {
-			if e.Value.(*cacheNode[K, V]).key == key {
-				lru.list.MoveToFront(e)
-				break
-			}
-		}
-		return node.value, true
+	lru.lock()
+	defer lru.unlock()
+	if e, exists := lru.cache[key]; exists {
+		lru.list.MoveToFront(e) // 移动到列表头部，表示最近使用
+		lru.counter.hits.Add(1)
+		return e.Value.value, true
+	}
+	lru.counter.misses.Add(1)
+	var zero V
+	return zero, false
+}
+
+// Peek 获取值但不改变使用顺序，也不计入命中统计
+func (lru *LRUCache[K, V]) Peek(key K) (V, bool) {
+	lru.lock()
+	defer lru.unlock()
+	if e, exists := lru.cache[key]; exists {
+		return e.Value.value, true
 	}
 	var zero V
 	return zero, false
 }
 
 func (lru *LRUCache[K, V]) Put(key K, value V) {
-	if node, exists := lru.cache[key]; exists {
+	lru.lock()
+	if e, exists := lru.cache[key]; exists {
 		// 更新值并移动到列表头部
-		node.value = value
-		for e := lru.list.Front(); e != nil; e = e.Next() {
-			if e.Value.(*cacheNode[K, V]).key == key {
-				lru.list.MoveToFront(e)
-				break
-			}
-		}
-	} else {
-		if len(lru.cache) >= lru.capacity {
-			// 移除最久未使用的元素
-			back := lru.list.Back()
-			if back != nil {
-				lru.list.Remove(back)
-				delete(lru.cache, back.Value.(*cacheNode[K, V]).key)
-			}
-		}
-		newNode := &cacheNode[K, V]{key: key, value: value}
-		lru.list.PushFront(newNode)
-		lru.cache[key] = newNode
+		e.Value.value = value
+		lru.list.MoveToFront(e)
+		lru.unlock()
+		return
+	}
+	lru.cache[key] = lru.list.Prepend(cacheNode[K, V]{key: key, value: value})
+	evicted, onEvict := lru.evict(lru.capacity), lru.onEvict
+	lru.unlock()
+	notifyEvict(evicted, onEvict)
+}
+
+// Delete 删除指定的键，返回键是否存在
+func (lru *LRUCache[K, V]) Delete(key K) bool {
+	lru.lock()
+	defer lru.unlock()
+	e, exists := lru.cache[key]
+	if !exists {
+		return false
+	}
+	lru.list.Remove(e)
+	delete(lru.cache, key)
+	return true
+}
+
+func (lru *LRUCache[K, V]) Len() int {
+	lru.lock()
+	defer lru.unlock()
+	return lru.list.Length()
+}
+
+// Keys 按最近使用到最久未使用的顺序返回所有键
+func (lru *LRUCache[K, V]) Keys() []K {
+	lru.lock()
+	defer lru.unlock()
+	keys := make([]K, 0, lru.list.Length())
+	for node := range lru.list.All() {
+		keys = append(keys, node.key)
+	}
+	return keys
+}
+
+// Resize 调整容量，缩容时立即淘汰多余的元素，返回被淘汰的数量
+func (lru *LRUCache[K, V]) Resize(capacity int) int {
+	lru.lock()
+	lru.capacity = capacity
+	evicted, onEvict := lru.evict(capacity), lru.onEvict
+	lru.unlock()
+	notifyEvict(evicted, onEvict)
+	return len(evicted)
+}
+
+func (lru *LRUCache[K, V]) Stats() CacheStats {
+	return lru.counter.snapshot()
+}
+
+// evict 从尾部淘汰元素直到数量不超过 capacity，调用方需持有锁
+func (lru *LRUCache[K, V]) evict(capacity int) []cacheNode[K, V] {
+	var evicted []cacheNode[K, V]
+	for lru.list.Length() > max(capacity, 0) {
+		node, _ := lru.list.PopBack() // 移除最久未使用的元素
+		delete(lru.cache, node.key)
+		lru.counter.evictions.Add(1)
+		evicted = append(evicted, node)
+	}
+	return evicted
+}
+
+func notifyEvict[K comparable, V any](evicted []cacheNode[K, V], onEvict func(key K, value V)) {
+	if onEvict == nil {
+		return
+	}
+	for _, node := range evicted {
+		onEvict(node.key, node.value)
 	}
 }
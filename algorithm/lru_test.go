@@ -1,6 +1,9 @@
 package algorithm
 
-import "testing"
+import (
+	"sync"
+	"testing"
+)
 
 func TestLRUCache(t *testing.T) {
 	cache := NewLRUCache[int, string](2)
@@ -32,3 +35,75 @@ func TestLRUCache(t *testing.T) {
 		t.Errorf("Expected to get 'four' for key 4, got '%s'", val)
 	}
 }
+
+func TestLRUCache_Operations(t *testing.T) {
+	cache := NewLRUCache[string, int](3)
+	var evicted []string
+	cache.OnEvict(func(key string, value int) {
+		evicted = append(evicted, key)
+	})
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Put("c", 3)
+
+	// Peek 不应改变使用顺序
+	if val, ok := cache.Peek("a"); !ok || val != 1 {
+		t.Errorf("Expected to peek 1 for key a, got %d", val)
+	}
+	if keys := cache.Keys(); len(keys) != 3 || keys[0] != "c" || keys[2] != "a" {
+		t.Errorf("Unexpected key order %v", keys)
+	}
+
+	cache.Get("a")
+	cache.Put("d", 4) // 淘汰 b
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("Expected b to be evicted, got %v", evicted)
+	}
+
+	if !cache.Delete("c") {
+		t.Error("Expected key c to be deleted")
+	}
+	if cache.Delete("c") {
+		t.Error("Expected deleting a missing key to return false")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 elements, got %d", cache.Len())
+	}
+
+	// 缩容时淘汰最久未使用的 a
+	if n := cache.Resize(1); n != 1 {
+		t.Errorf("Expected 1 element evicted by Resize, got %d", n)
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected key a to be evicted by Resize")
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestSyncLRUCache_Concurrent(t *testing.T) {
+	cache := NewSyncLRUCache[int, int](64)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(base int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				cache.Put(base*1000+j, j)
+				cache.Get(base*1000 + j - 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if cache.Len() != 64 {
+		t.Errorf("Expected 64 elements, got %d", cache.Len())
+	}
+	if stats := cache.Stats(); stats.Evictions != 16*1000-64 {
+		t.Errorf("Expected %d evictions, got %d", 16*1000-64, stats.Evictions)
+	}
+}
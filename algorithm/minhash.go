@@ -0,0 +1,299 @@
+package algorithm
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+const mersennePrime = (1 << 61) - 1 // 2^61-1，用于通用哈希族 (a*x+b) mod p
+
+// MinHasher 生成 MinHash 签名，两个签名中相等位置的比例是 Jaccard 相似度的无偏估计
+// 相同 numHashes 和 seed 创建的 MinHasher 生成的签名可以互相比较
+type MinHasher struct {
+	a []uint64
+	b []uint64
+}
+
+func NewMinHasher(numHashes int, seed int64) *MinHasher {
+	r := rand.New(rand.NewSource(seed))
+	m := &MinHasher{a: make([]uint64, numHashes), b: make([]uint64, numHashes)}
+	for i := 0; i < numHashes; i++ {
+		m.a[i] = uint64(r.Int63n(mersennePrime-1)) + 1 // a 不能为 0
+		m.b[i] = uint64(r.Int63n(mersennePrime))
+	}
+	return m
+}
+
+// NumHashes 返回签名长度
+func (m *MinHasher) NumHashes() int {
+	return len(m.a)
+}
+
+// Signature 计算元素集合的签名，重复元素不影响结果，空集合的签名全部为 math.MaxUint64
+func (m *MinHasher) Signature(tokens []string) []uint64 {
+	sig := make([]uint64, len(m.a))
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	for _, token := range tokens {
+		x := hashToken(token)
+		for i := range sig {
+			if h := mulAddMod(m.a[i], x, m.b[i]); h < sig[i] {
+				sig[i] = h
+			}
+		}
+	}
+	return sig
+}
+
+// EstimateJaccard 用两个签名估计原集合的 Jaccard 相似度
+func EstimateJaccard(sigA, sigB []uint64) float64 {
+	if len(sigA) == 0 || len(sigA) != len(sigB) {
+		return 0.0
+	}
+	equal := 0
+	for i := range sigA {
+		if sigA[i] == sigB[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(sigA))
+}
+
+// Shingles 把文本切分为长度为 k 的 rune 片段并去重，作为 MinHash 的输入
+func Shingles(text string, k int) []string {
+	runes := []rune(text)
+	if k <= 0 || len(runes) == 0 {
+		return nil
+	}
+	if len(runes) <= k {
+		return []string{text}
+	}
+	seen := make(map[string]struct{}, len(runes)-k+1)
+	shingles := make([]string, 0, len(runes)-k+1)
+	for i := 0; i+k <= len(runes); i++ {
+		s := string(runes[i : i+k])
+		if _, exists := seen[s]; !exists {
+			seen[s] = struct{}{}
+			shingles = append(shingles, s)
+		}
+	}
+	return shingles
+}
+
+func hashToken(token string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(token))
+	return reduceMersenne(h.Sum64())
+}
+
+// mulAddMod 计算 (a*x+b) mod 2^61-1，要求 a、x、b 均小于 2^61-1
+func mulAddMod(a, x, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, x)
+	// 2^61 ≡ 1 (mod p)，把 128 位乘积按 61 位拆分后相加
+	v := (hi<<3 | lo>>61) + (lo & mersennePrime) + b
+	return reduceMersenne(v)
+}
+
+func reduceMersenne(v uint64) uint64 {
+	v = (v & mersennePrime) + (v >> 61)
+	if v >= mersennePrime {
+		v -= mersennePrime
+	}
+	return v
+}
+
+// LSHPair LSH 找到的候选对，Similarity 为签名估计的 Jaccard 相似度
+type LSHPair struct {
+	A, B       string
+	Similarity float64
+}
+
+// LSHMatch Query 的查询结果
+type LSHMatch struct {
+	ID         string
+	Similarity float64
+}
+
+// LSHIndex 基于 MinHash 签名分段（banding）的局部敏感哈希索引
+// 签名被分成 bands 段，每段 rows 行，只要有一段完全相同就成为候选
+type LSHIndex struct {
+	mu        sync.RWMutex
+	hasher    *MinHasher
+	threshold float64
+	bands     int
+	rows      int
+	buckets   []map[uint64][]string // 每段一个哈希桶
+	sigs      map[string][]uint64
+}
+
+// NewLSHIndex 创建 LSH 索引，根据 threshold 自动选择分段方式，使 (1/bands)^(1/rows) 尽量接近 threshold
+func NewLSHIndex(numHashes int, threshold float64, seed int64) *LSHIndex {
+	bands, rows := lshParams(numHashes, threshold)
+	idx := &LSHIndex{
+		hasher:    NewMinHasher(numHashes, seed),
+		threshold: threshold,
+		bands:     bands,
+		rows:      rows,
+		buckets:   make([]map[uint64][]string, bands),
+		sigs:      make(map[string][]uint64),
+	}
+	for i := range idx.buckets {
+		idx.buckets[i] = make(map[uint64][]string)
+	}
+	return idx
+}
+
+func lshParams(numHashes int, threshold float64) (bands, rows int) {
+	bands, rows = numHashes, 1
+	best := math.Inf(1)
+	for b := 1; b <= numHashes; b++ {
+		r := numHashes / b
+		diff := math.Abs(math.Pow(1/float64(b), 1/float64(r)) - threshold)
+		if diff < best {
+			best, bands, rows = diff, b, r
+		}
+	}
+	return bands, rows
+}
+
+// Bands 返回分段数和每段的行数
+func (idx *LSHIndex) Bands() (bands, rows int) {
+	return idx.bands, idx.rows
+}
+
+// Add 加入一个文档，id 已存在时覆盖旧的签名
+func (idx *LSHIndex) Add(id string, tokens []string) {
+	sig := idx.hasher.Signature(tokens)
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if old, exists := idx.sigs[id]; exists {
+		idx.unindex(id, old)
+	}
+	idx.sigs[id] = sig
+	for band := 0; band < idx.bands; band++ {
+		key := idx.bandKey(sig, band)
+		idx.buckets[band][key] = append(idx.buckets[band][key], id)
+	}
+}
+
+// Remove 删除文档，返回文档是否存在
+func (idx *LSHIndex) Remove(id string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	sig, exists := idx.sigs[id]
+	if !exists {
+		return false
+	}
+	idx.unindex(id, sig)
+	delete(idx.sigs, id)
+	return true
+}
+
+func (idx *LSHIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.sigs)
+}
+
+// Query 返回与 tokens 估计相似度不低于阈值的文档，按相似度从高到低排序
+func (idx *LSHIndex) Query(tokens []string) []LSHMatch {
+	sig := idx.hasher.Signature(tokens)
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	seen := make(map[string]struct{})
+	var result []LSHMatch
+	for band := 0; band < idx.bands; band++ {
+		for _, id := range idx.buckets[band][idx.bandKey(sig, band)] {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			if s := EstimateJaccard(sig, idx.sigs[id]); s >= idx.threshold {
+				result = append(result, LSHMatch{ID: id, Similarity: s})
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Similarity != result[j].Similarity {
+			return result[i].Similarity > result[j].Similarity
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// CandidatePairs 返回索引中估计相似度不低于阈值的所有文档对，按相似度从高到低排序
+func (idx *LSHIndex) CandidatePairs() []LSHPair {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	seen := make(map[[2]string]struct{})
+	var pairs []LSHPair
+	for _, buckets := range idx.buckets {
+		for _, ids := range buckets {
+			for i := 0; i < len(ids); i++ {
+				for j := i + 1; j < len(ids); j++ {
+					a, b := ids[i], ids[j]
+					if a > b {
+						a, b = b, a
+					}
+					if _, ok := seen[[2]string{a, b}]; ok {
+						continue
+					}
+					seen[[2]string{a, b}] = struct{}{}
+					if s := EstimateJaccard(idx.sigs[a], idx.sigs[b]); s >= idx.threshold {
+						pairs = append(pairs, LSHPair{A: a, B: b, Similarity: s})
+					}
+				}
+			}
+		}
+	}
+	sortPairs(pairs)
+	return pairs
+}
+
+// unindex 把文档从所有哈希桶中移除，调用方需持有写锁
+func (idx *LSHIndex) unindex(id string, sig []uint64) {
+	for band := 0; band < idx.bands; band++ {
+		key := idx.bandKey(sig, band)
+		ids := idx.buckets[band][key]
+		for i, other := range ids {
+			if other == id {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(idx.buckets[band], key)
+		} else {
+			idx.buckets[band][key] = ids
+		}
+	}
+}
+
+func (idx *LSHIndex) bandKey(sig []uint64, band int) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, v := range sig[band*idx.rows : (band+1)*idx.rows] {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+func sortPairs(pairs []LSHPair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+}
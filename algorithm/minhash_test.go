@@ -0,0 +1,74 @@
+package algorithm
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestJaccardSimilarityBySorted(t *testing.T) {
+	setA := []int{3, 1, 2, 2, 3}
+	setB := []int{4, 3, 2, 2}
+
+	// {1,2,3} 与 {2,3,4} 的交集为 2，并集为 4
+	if got := JaccardSimilarityBySorted(setA, setB); got != 0.5 {
+		t.Errorf("Expected 0.5, got %f", got)
+	}
+	if got := JaccardSimilarity(setA, setB); got != 0.5 {
+		t.Errorf("Expected 0.5, got %f", got)
+	}
+	if fmt.Sprint(setA) != "[3 1 2 2 3]" || fmt.Sprint(setB) != "[4 3 2 2]" {
+		t.Errorf("Input slices must not be modified, got %v and %v", setA, setB)
+	}
+}
+
+func TestMinHashEstimate(t *testing.T) {
+	hasher := NewMinHasher(256, 42)
+	var setA, setB []string
+	for i := 0; i < 100; i++ {
+		setA = append(setA, fmt.Sprintf("token-%d", i))
+		setB = append(setB, fmt.Sprintf("token-%d", i+50))
+	}
+	exact := JaccardSimilarity(setA, setB) // 50/150
+	estimate := EstimateJaccard(hasher.Signature(setA), hasher.Signature(setB))
+	if math.Abs(estimate-exact) > 0.1 {
+		t.Errorf("Estimate %f is too far from exact %f", estimate, exact)
+	}
+
+	if got := EstimateJaccard(hasher.Signature(setA), hasher.Signature(append(setA, setA...))); got != 1.0 {
+		t.Errorf("Duplicates must not change the signature, got %f", got)
+	}
+}
+
+func TestLSHIndex(t *testing.T) {
+	idx := NewLSHIndex(128, 0.6, 7)
+	if bands, rows := idx.Bands(); bands*rows > 128 {
+		t.Fatalf("Invalid band parameters %d x %d", bands, rows)
+	}
+
+	docs := map[string]string{
+		"a": "the quick brown fox jumps over the lazy dog",
+		"b": "the quick brown fox jumped over the lazy dog",
+		"c": "lorem ipsum dolor sit amet consectetur adipiscing",
+	}
+	for id, text := range docs {
+		idx.Add(id, Shingles(text, 3))
+	}
+
+	pairs := idx.CandidatePairs()
+	if len(pairs) != 1 || pairs[0].A != "a" || pairs[0].B != "b" {
+		t.Errorf("Expected only (a, b) as candidates, got %v", pairs)
+	}
+
+	matches := idx.Query(Shingles("the quick brown fox jumps over a lazy dog", 3))
+	if len(matches) == 0 || matches[0].ID != "a" {
+		t.Errorf("Expected a to be the best match, got %v", matches)
+	}
+
+	if !idx.Remove("b") || idx.Len() != 2 {
+		t.Errorf("Expected 2 documents after remove, got %d", idx.Len())
+	}
+	if pairs := idx.CandidatePairs(); len(pairs) != 0 {
+		t.Errorf("Expected no candidates after remove, got %v", pairs)
+	}
+}
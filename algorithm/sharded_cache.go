@@ -0,0 +1,143 @@
+package algorithm
+
+import (
+	"hash/maphash"
+)
+
+// ShardPolicy 分片内部使用的缓存策略
+type ShardPolicy int
+
+const (
+	ShardPolicyLRU      ShardPolicy = iota // 每个分片是一个 LRUCache，忽略过期时间
+	ShardPolicyDeadline                    // 每个分片是一个 TimeoutCache，按过期时间淘汰
+)
+
+// cacheShard 统一 LRUCache 与 TimeoutCache 的分片接口
+type cacheShard[K comparable, V any] interface {
+	get(key K) (V, bool)
+	set(key K, value V, deadline int64)
+	delete(key K) bool
+	len() int
+	stats() CacheStats
+}
+
+type lruShard[K comparable, V any] struct {
+	*LRUCache[K, V]
+}
+
+func (s lruShard[K, V]) get(key K) (V, bool)         { return s.Get(key) }
+func (s lruShard[K, V]) set(key K, value V, _ int64) { s.Put(key, value) }
+func (s lruShard[K, V]) delete(key K) bool           { return s.Delete(key) }
+func (s lruShard[K, V]) len() int                    { return s.Len() }
+func (s lruShard[K, V]) stats() CacheStats           { return s.Stats() }
+
+type timeoutShard[K comparable, V any] struct {
+	*TimeoutCache[K, V]
+}
+
+func (s timeoutShard[K, V]) get(key K) (V, bool)                { return s.Get(key) }
+func (s timeoutShard[K, V]) set(key K, value V, deadline int64) { s.Set(key, value, deadline) }
+func (s timeoutShard[K, V]) delete(key K) bool                  { return s.Delete(key) }
+func (s timeoutShard[K, V]) len() int                           { return s.Size() }
+func (s timeoutShard[K, V]) stats() CacheStats                  { return s.Stats() }
+
+type shardedConfig[K comparable] struct {
+	policy ShardPolicy
+	hash   func(key K) uint64
+}
+
+type ShardedOption[K comparable] func(*shardedConfig[K])
+
+// WithShardHash 自定义键到分片的哈希函数，默认使用 maphash
+func WithShardHash[K comparable](hash func(key K) uint64) ShardedOption[K] {
+	return func(c *shardedConfig[K]) {
+		c.hash = hash
+	}
+}
+
+// WithShardPolicy 设置分片的缓存策略，默认 ShardPolicyLRU
+func WithShardPolicy[K comparable](policy ShardPolicy) ShardedOption[K] {
+	return func(c *shardedConfig[K]) {
+		c.policy = policy
+	}
+}
+
+// ShardedCache 将键分散到多个独立加锁的分片上，降低读多写少场景下的锁竞争
+type ShardedCache[K comparable, V any] struct {
+	shards []cacheShard[K, V]
+	hash   func(key K) uint64
+}
+
+// NewShardedCache 创建分片缓存，capacity 为总容量，平均分配到每个分片
+func NewShardedCache[K comparable, V any](shardNum, capacity int, opts ...ShardedOption[K]) *ShardedCache[K, V] {
+	if shardNum <= 0 {
+		shardNum = 1
+	}
+	seed := maphash.MakeSeed()
+	cfg := &shardedConfig[K]{
+		policy: ShardPolicyLRU,
+		hash: func(key K) uint64 {
+			return maphash.Comparable(seed, key)
+		},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	perShard := (capacity + shardNum - 1) / shardNum // 向上取整，保证总容量不小于 capacity
+	sc := &ShardedCache[K, V]{
+		shards: make([]cacheShard[K, V], shardNum),
+		hash:   cfg.hash,
+	}
+	for i := range sc.shards {
+		switch cfg.policy {
+		case ShardPolicyDeadline:
+			sc.shards[i] = timeoutShard[K, V]{NewTimeoutCache[K, V](perShard)}
+		default:
+			sc.shards[i] = lruShard[K, V]{NewSyncLRUCache[K, V](perShard)}
+		}
+	}
+	return sc
+}
+
+func (sc *ShardedCache[K, V]) shard(key K) cacheShard[K, V] {
+	return sc.shards[sc.hash(key)%uint64(len(sc.shards))]
+}
+
+func (sc *ShardedCache[K, V]) Get(key K) (V, bool) {
+	return sc.shard(key).get(key)
+}
+
+// Set 写入一个永不过期的值
+func (sc *ShardedCache[K, V]) Set(key K, value V) {
+	sc.shard(key).set(key, value, 0)
+}
+
+// SetWithDeadline 写入带过期时间戳（毫秒）的值，ShardPolicyLRU 下 deadline 会被忽略
+func (sc *ShardedCache[K, V]) SetWithDeadline(key K, value V, deadline int64) {
+	sc.shard(key).set(key, value, deadline)
+}
+
+func (sc *ShardedCache[K, V]) Delete(key K) bool {
+	return sc.shard(key).delete(key)
+}
+
+func (sc *ShardedCache[K, V]) Len() int {
+	total := 0
+	for _, s := range sc.shards {
+		total += s.len()
+	}
+	return total
+}
+
+// Stats 汇总所有分片的命中统计
+func (sc *ShardedCache[K, V]) Stats() CacheStats {
+	var total CacheStats
+	for _, s := range sc.shards {
+		st := s.stats()
+		total.Hits += st.Hits
+		total.Misses += st.Misses
+		total.Evictions += st.Evictions
+	}
+	return total
+}
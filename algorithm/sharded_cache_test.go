@@ -0,0 +1,68 @@
+package algorithm
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedCache_LRU(t *testing.T) {
+	// 所有键落在同一个分片，便于验证淘汰顺序
+	cache := NewShardedCache[int, string](4, 8, WithShardHash(func(key int) uint64 { return 0 }))
+
+	cache.Set(1, "one")
+	cache.Set(2, "two")
+	cache.Set(3, "three") // 每个分片容量为 2，淘汰 1
+
+	if _, ok := cache.Get(1); ok {
+		t.Error("Expected key 1 to be evicted")
+	}
+	if val, ok := cache.Get(3); !ok || val != "three" {
+		t.Errorf("Expected 'three' for key 3, got '%s'", val)
+	}
+	if !cache.Delete(2) || cache.Len() != 1 {
+		t.Errorf("Expected 1 element after delete, got %d", cache.Len())
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestShardedCache_Deadline(t *testing.T) {
+	cache := NewShardedCache[string, int](4, 100, WithShardPolicy[string](ShardPolicyDeadline))
+
+	cache.SetWithDeadline("expired", 1, time.Now().Add(-time.Second).UnixMilli())
+	cache.Set("permanent", 2)
+
+	if _, ok := cache.Get("expired"); ok {
+		t.Error("Expected expired key to be missing")
+	}
+	if val, ok := cache.Get("permanent"); !ok || val != 2 {
+		t.Errorf("Expected 2 for permanent key, got %d", val)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 element, got %d", cache.Len())
+	}
+}
+
+func TestShardedCache_Concurrent(t *testing.T) {
+	cache := NewShardedCache[int, int](16, 1024)
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(base int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				cache.Set(base*500+j, j)
+				cache.Get(j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if cache.Len() > 1024 {
+		t.Errorf("Expected at most 1024 elements, got %d", cache.Len())
+	}
+}
@@ -0,0 +1,80 @@
+package algorithm
+
+import (
+	"cmp"
+	"slices"
+)
+
+// SortedSlice 始终保持有序的切片，插入和删除为 O(n)，查询为 O(log n)
+// 允许重复元素，相等的元素按插入顺序排列
+type SortedSlice[T any] struct {
+	items   []T
+	compare func(a, b T) int
+}
+
+func NewSortedSlice[T cmp.Ordered](values ...T) *SortedSlice[T] {
+	return NewSortedSliceFunc(cmp.Compare[T], values...)
+}
+
+// NewSortedSliceFunc 使用自定义比较函数创建有序切片，compare 返回负数表示 a 排在 b 前面
+func NewSortedSliceFunc[T any](compare func(a, b T) int, values ...T) *SortedSlice[T] {
+	items := slices.Clone(values)
+	slices.SortStableFunc(items, compare)
+	return &SortedSlice[T]{items: items, compare: compare}
+}
+
+func (s *SortedSlice[T]) Len() int {
+	return len(s.items)
+}
+
+// Insert 插入元素并返回其下标
+func (s *SortedSlice[T]) Insert(value T) int {
+	i := UpperBoundFunc(s.items, value, s.compare)
+	s.items = slices.Insert(s.items, i, value)
+	return i
+}
+
+// Delete 删除一个与 value 相等的元素，不存在时返回 false
+func (s *SortedSlice[T]) Delete(value T) bool {
+	i, found := BinarySearchFunc(s.items, value, s.compare)
+	if !found {
+		return false
+	}
+	s.items = slices.Delete(s.items, i, i+1)
+	return true
+}
+
+// Contains 判断是否存在与 value 相等的元素
+func (s *SortedSlice[T]) Contains(value T) bool {
+	_, found := BinarySearchFunc(s.items, value, s.compare)
+	return found
+}
+
+// Rank 返回排在 value 前面的元素数量，即 value 插入后的最小下标
+func (s *SortedSlice[T]) Rank(value T) int {
+	return LowerBoundFunc(s.items, value, s.compare)
+}
+
+// Select 返回下标为 k 的元素（从 0 开始），越界时返回 false
+func (s *SortedSlice[T]) Select(k int) (T, bool) {
+	if k < 0 || k >= len(s.items) {
+		var zero T
+		return zero, false
+	}
+	return s.items[k], true
+}
+
+// Range 返回位于 [lo, hi) 之间的元素，返回的切片是副本
+func (s *SortedSlice[T]) Range(lo, hi T) []T {
+	start := LowerBoundFunc(s.items, lo, s.compare)
+	end := LowerBoundFunc(s.items, hi, s.compare)
+	if start >= end {
+		return nil
+	}
+	return slices.Clone(s.items[start:end])
+}
+
+// Values 返回所有元素的副本
+func (s *SortedSlice[T]) Values() []T {
+	return slices.Clone(s.items)
+}
@@ -0,0 +1,62 @@
+package algorithm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSortedSlice(t *testing.T) {
+	s := NewSortedSlice(5, 1, 3)
+	s.Insert(4)
+	s.Insert(3)
+	if got := fmt.Sprint(s.Values()); got != "[1 3 3 4 5]" {
+		t.Errorf("Unexpected values %s", got)
+	}
+
+	if r := s.Rank(4); r != 3 {
+		t.Errorf("Expected rank 3, got %d", r)
+	}
+	if v, ok := s.Select(1); !ok || v != 3 {
+		t.Errorf("Expected 3 at index 1, got %d", v)
+	}
+	if _, ok := s.Select(5); ok {
+		t.Error("Expected out of range select to fail")
+	}
+	if got := fmt.Sprint(s.Range(3, 5)); got != "[3 3 4]" {
+		t.Errorf("Unexpected range %s", got)
+	}
+
+	if !s.Delete(3) || !s.Contains(3) || s.Len() != 4 {
+		t.Error("Expected only one 3 to be deleted")
+	}
+	if s.Delete(42) {
+		t.Error("Expected deleting a missing value to fail")
+	}
+}
+
+func TestSortedSliceLeaderboard(t *testing.T) {
+	type player struct {
+		name  string
+		score int
+	}
+	// 分数从高到低排序
+	board := NewSortedSliceFunc(func(a, b player) int { return b.score - a.score })
+	board.Insert(player{"alice", 80})
+	board.Insert(player{"bob", 95})
+	board.Insert(player{"carol", 80})
+	board.Insert(player{"dave", 60})
+
+	if top, _ := board.Select(0); top.name != "bob" {
+		t.Errorf("Expected bob first, got %s", top.name)
+	}
+	// 分数为 80 的玩家前面只有 bob
+	if r := board.Rank(player{score: 80}); r != 1 {
+		t.Errorf("Expected rank 1, got %d", r)
+	}
+	if second, _ := board.Select(2); second.name != "carol" {
+		t.Errorf("Expected equal scores to keep insertion order, got %s", second.name)
+	}
+	if got := board.Range(player{score: 90}, player{score: 60}); len(got) != 2 {
+		t.Errorf("Expected 2 players in (60, 90], got %v", got)
+	}
+}
@@ -2,22 +2,76 @@ package algorithm
 
 import (
 	"container/heap"
+	"math"
 	"sync"
 	"time"
+
+	"GoTools/clock"
+)
+
+// EvictPolicy 缓存已满时选择淘汰对象的策略
+type EvictPolicy int
+
+const (
+	EvictEarliestDeadline EvictPolicy = iota // 淘汰最早过期的元素，永不过期的元素最后淘汰
+	EvictLRU                                 // 淘汰最久未访问的元素
+	EvictLFU                                 // 淘汰访问次数最少的元素，次数相同时淘汰最久未访问的
 )
 
 type HeapNode[K comparable, T any] struct {
-	Key      K // 唯一标识符
-	Value    T
-	index    int   // 在堆中的索引
-	deadline int64 // 过期时间戳
+	Key        K // 唯一标识符
+	Value      T
+	index      int                              // 在堆中的索引
+	deadline   int64                            // 过期时间戳
+	elem       *DoubleListNode[*HeapNode[K, T]] // EvictLRU 时在访问链表中的位置
+	lfuIndex   int                              // EvictLFU 时在访问频率堆中的索引
+	freq       uint64                           // 访问次数
+	lastAccess uint64                           // 最近一次访问的逻辑时间
+}
+
+type timeoutCacheConfig struct {
+	janitorInterval time.Duration
+	policy          EvictPolicy
+	clock           clock.Clock
+}
+
+type TimeoutCacheOption func(*timeoutCacheConfig)
+
+// WithJanitor 启动后台协程，每隔 interval 清理一次已过期的元素，需调用 Close 停止
+func WithJanitor(interval time.Duration) TimeoutCacheOption {
+	return func(c *timeoutCacheConfig) {
+		c.janitorInterval = interval
+	}
+}
+
+// WithClock 指定判断过期使用的时间来源，测试中可传入 clock.FakeClock
+func WithClock(c clock.Clock) TimeoutCacheOption {
+	return func(cfg *timeoutCacheConfig) {
+		cfg.clock = c
+	}
+}
+
+// WithEvictPolicy 设置容量已满时的淘汰策略，默认 EvictEarliestDeadline
+func WithEvictPolicy(policy EvictPolicy) TimeoutCacheOption {
+	return func(c *timeoutCacheConfig) {
+		c.policy = policy
+	}
 }
 
 type TimeoutCache[K comparable, T any] struct {
 	Capacity   int
 	heap       []*HeapNode[K, T]     // 最小堆
 	cache      map[K]*HeapNode[K, T] // 哈希表
-	sync.Mutex                       // 互斥锁，确保线程安全
+	counter    cacheCounter          // 命中统计
+	policy     EvictPolicy
+	lru        *DoubleList[*HeapNode[K, T]] // EvictLRU 使用，头部为最近访问
+	lfu        lfuQueue[K, T]               // EvictLFU 使用
+	tick       uint64                       // 逻辑时钟，每次访问自增
+	onExpire   func(key K, value T)
+	clock      clock.Clock
+	closeCh    chan struct{}
+	closeOnce  sync.Once
+	sync.Mutex // 互斥锁，确保线程安全
 }
 
 // 使用heap.Interface实现最小堆的接口
@@ -25,7 +79,7 @@ func (tc *TimeoutCache[K, T]) Len() int {
 	return len(tc.heap)
 }
 func (tc *TimeoutCache[K, T]) Less(i, j int) bool {
-	return tc.heap[i].deadline < tc.heap[j].deadline
+	return expireAt(tc.heap[i].deadline) < expireAt(tc.heap[j].deadline)
 }
 func (tc *TimeoutCache[K, T]) Swap(i, j int) {
 	tc.heap[i], tc.heap[j] = tc.heap[j], tc.heap[i]
@@ -50,12 +104,61 @@ func (tc *TimeoutCache[K, T]) Pop() any {
 	return node
 }
 
-func NewTimeoutCache[K comparable, T any](capacity int) *TimeoutCache[K, T] {
-	return &TimeoutCache[K, T]{
+// deadline <= 0 表示永不过期，排在堆的最后
+func expireAt(deadline int64) int64 {
+	if deadline <= 0 {
+		return math.MaxInt64
+	}
+	return deadline
+}
+
+// lfuQueue 按访问次数排序的最小堆
+type lfuQueue[K comparable, T any] []*HeapNode[K, T]
+
+func (q lfuQueue[K, T]) Len() int { return len(q) }
+func (q lfuQueue[K, T]) Less(i, j int) bool {
+	if q[i].freq != q[j].freq {
+		return q[i].freq < q[j].freq
+	}
+	return q[i].lastAccess < q[j].lastAccess
+}
+func (q lfuQueue[K, T]) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].lfuIndex = i
+	q[j].lfuIndex = j
+}
+func (q *lfuQueue[K, T]) Push(x any) {
+	node := x.(*HeapNode[K, T])
+	node.lfuIndex = len(*q)
+	*q = append(*q, node)
+}
+func (q *lfuQueue[K, T]) Pop() any {
+	old := *q
+	node := old[len(old)-1]
+	old[len(old)-1] = nil
+	node.lfuIndex = -1
+	*q = old[:len(old)-1]
+	return node
+}
+
+func NewTimeoutCache[K comparable, T any](capacity int, opts ...TimeoutCacheOption) *TimeoutCache[K, T] {
+	cfg := &timeoutCacheConfig{policy: EvictEarliestDeadline, clock: clock.New()}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	tc := &TimeoutCache[K, T]{
 		Capacity: capacity,
 		heap:     make([]*HeapNode[K, T], 0, capacity),
 		cache:    make(map[K]*HeapNode[K, T]),
+		policy:   cfg.policy,
+		lru:      NewDoubleList[*HeapNode[K, T]](),
+		closeCh:  make(chan struct{}),
+		clock:    cfg.clock,
 	}
+	if cfg.janitorInterval > 0 {
+		go tc.janitor(cfg.janitorInterval)
+	}
+	return tc
 }
 
 func (tc *TimeoutCache[K, T]) updateHeapIndices() {
@@ -66,45 +169,195 @@ func (tc *TimeoutCache[K, T]) updateHeapIndices() {
 	}
 }
 
+// OnExpire 设置过期回调，元素在 Get、后台清理或写入前的清理中被发现过期时触发
+// 回调在释放锁之后执行
+func (tc *TimeoutCache[K, T]) OnExpire(fn func(key K, value T)) {
+	tc.Lock()
+	defer tc.Unlock()
+	tc.onExpire = fn
+}
+
 // 懒惰删除：在获取时检查过期时间
 func (tc *TimeoutCache[K, T]) Get(key K) (T, bool) {
 	tc.Lock()
-	defer tc.Unlock()
-	currentTimeMillis := time.Now().UnixMilli()
+	currentTimeMillis := tc.clock.Now().UnixMilli()
 	if node, exists := tc.cache[key]; exists {
 		if node.deadline > 0 && node.deadline < currentTimeMillis {
 			// 如果节点已过期，删除它
-			heap.Remove(tc, node.index)
-			delete(tc.cache, key) // 从哈希表中删除
+			tc.remove(node)
+			tc.counter.misses.Add(1)
+			onExpire := tc.onExpire
+			tc.Unlock()
+			if onExpire != nil {
+				onExpire(node.Key, node.Value)
+			}
 			return *new(T), false
 		}
+		tc.touch(node)
+		tc.counter.hits.Add(1)
+		tc.Unlock()
 		return node.Value, true
 	}
+	tc.counter.misses.Add(1)
+	tc.Unlock()
 	return *new(T), false
 }
 
+// Set 写入值，deadline 为过期的毫秒时间戳，<= 0 表示永不过期
 func (tc *TimeoutCache[K, T]) Set(key K, value T, deadline int64) {
 	tc.Lock()
-	defer tc.Unlock()
-
 	if node, exists := tc.cache[key]; exists {
-		// 移除最小堆中的旧节点
-		heap.Remove(tc, node.index)
-		// 更新已存在的节点
+		// 更新已存在的节点并调整其在堆中的位置
 		node.Value = value
 		node.deadline = deadline
-		// 重新添加到堆中
-		heap.Push(tc, node)
-		tc.cache[key] = node
-	} else {
-		if len(tc.heap) >= tc.Capacity {
-			// 如果堆已满，移除最小的节点
-			oldest := heap.Pop(tc).(*HeapNode[K, T])
-			delete(tc.cache, oldest.Key)
+		heap.Fix(tc, node.index)
+		tc.touch(node)
+		tc.Unlock()
+		return
+	}
+
+	var expired []*HeapNode[K, T]
+	if len(tc.heap) >= tc.Capacity {
+		// 优先清理已过期的元素，仍然不够时才按策略淘汰
+		expired = tc.removeExpired(tc.clock.Now().UnixMilli())
+		for len(tc.heap) > 0 && len(tc.heap) >= tc.Capacity {
+			tc.remove(tc.victim())
+			tc.counter.evictions.Add(1)
+		}
+	}
+	// 创建新节点并添加到堆和哈希表中
+	newNode := &HeapNode[K, T]{Key: key, Value: value, deadline: deadline, lfuIndex: -1}
+	heap.Push(tc, newNode)
+	switch tc.policy {
+	case EvictLRU:
+		newNode.elem = tc.lru.Prepend(newNode)
+	case EvictLFU:
+		heap.Push(&tc.lfu, newNode)
+	}
+	tc.touch(newNode)
+	onExpire := tc.onExpire
+	tc.Unlock()
+	notifyExpire(expired, onExpire)
+}
+
+// SetWithTTL 写入值并在 ttl 之后过期，ttl <= 0 表示永不过期
+func (tc *TimeoutCache[K, T]) SetWithTTL(key K, value T, ttl time.Duration) {
+	var deadline int64
+	if ttl > 0 {
+		deadline = tc.clock.Now().Add(ttl).UnixMilli()
+	}
+	tc.Set(key, value, deadline)
+}
+
+// Delete 删除指定的键，返回键是否存在
+func (tc *TimeoutCache[K, T]) Delete(key K) bool {
+	tc.Lock()
+	defer tc.Unlock()
+	node, exists := tc.cache[key]
+	if !exists {
+		return false
+	}
+	tc.remove(node)
+	return true
+}
+
+// DeleteExpired 立即清理所有已过期的元素，返回清理的数量
+func (tc *TimeoutCache[K, T]) DeleteExpired() int {
+	tc.Lock()
+	expired := tc.removeExpired(tc.clock.Now().UnixMilli())
+	onExpire := tc.onExpire
+	tc.Unlock()
+	notifyExpire(expired, onExpire)
+	return len(expired)
+}
+
+// Size 返回当前元素数量（包含尚未被清理的过期元素），Len 留给 heap.Interface 使用
+func (tc *TimeoutCache[K, T]) Size() int {
+	tc.Lock()
+	defer tc.Unlock()
+	return len(tc.heap)
+}
+
+func (tc *TimeoutCache[K, T]) Stats() CacheStats {
+	return tc.counter.snapshot()
+}
+
+// Close 停止后台清理协程，可重复调用
+func (tc *TimeoutCache[K, T]) Close() {
+	tc.closeOnce.Do(func() {
+		close(tc.closeCh)
+	})
+}
+
+func (tc *TimeoutCache[K, T]) janitor(interval time.Duration) {
+	ticker := tc.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			tc.DeleteExpired()
+		case <-tc.closeCh:
+			return
+		}
+	}
+}
+
+// removeExpired 从堆顶开始弹出所有已过期的元素，调用方需持有锁
+func (tc *TimeoutCache[K, T]) removeExpired(now int64) []*HeapNode[K, T] {
+	var expired []*HeapNode[K, T]
+	for len(tc.heap) > 0 {
+		node := tc.heap[0]
+		if node.deadline <= 0 || node.deadline >= now {
+			break // 堆顶未过期，其余元素也不会过期
 		}
-		// 创建新节点并添加到堆和哈希表中
-		newNode := &HeapNode[K, T]{Key: key, Value: value, deadline: deadline}
-		heap.Push(tc, newNode)
-		tc.cache[key] = newNode
+		tc.remove(node)
+		expired = append(expired, node)
+	}
+	return expired
+}
+
+// victim 按淘汰策略选出要淘汰的元素，调用方需持有锁且缓存非空
+func (tc *TimeoutCache[K, T]) victim() *HeapNode[K, T] {
+	switch tc.policy {
+	case EvictLRU:
+		return tc.lru.Tail.Value
+	case EvictLFU:
+		return tc.lfu[0]
+	default:
+		return tc.heap[0]
+	}
+}
+
+// remove 把节点从堆、哈希表和淘汰策略的辅助结构中移除
+func (tc *TimeoutCache[K, T]) remove(node *HeapNode[K, T]) {
+	heap.Remove(tc, node.index)
+	if node.elem != nil {
+		tc.lru.Remove(node.elem)
+		node.elem = nil
+	}
+	if node.lfuIndex >= 0 && tc.policy == EvictLFU {
+		heap.Remove(&tc.lfu, node.lfuIndex)
+	}
+}
+
+// touch 记录一次访问
+func (tc *TimeoutCache[K, T]) touch(node *HeapNode[K, T]) {
+	tc.tick++
+	node.lastAccess = tc.tick
+	node.freq++
+	switch tc.policy {
+	case EvictLRU:
+		tc.lru.MoveToFront(node.elem)
+	case EvictLFU:
+		heap.Fix(&tc.lfu, node.lfuIndex)
+	}
+}
+
+func notifyExpire[K comparable, T any](expired []*HeapNode[K, T], onExpire func(key K, value T)) {
+	if onExpire == nil {
+		return
+	}
+	for _, node := range expired {
+		onExpire(node.Key, node.Value)
 	}
 }
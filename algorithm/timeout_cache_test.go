@@ -6,13 +6,16 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"GoTools/clock"
 )
 
 func TestTimeoutCache(t *testing.T) {
-	cache := NewTimeoutCache[string, int](5)
+	fc := clock.NewFakeClock(time.Now())
+	cache := NewTimeoutCache[string, int](5, WithClock(fc))
 
-	deadline := time.Now().Add(5 * time.Second).UnixMilli()
-	deadline2 := time.Now().UnixMilli() + 1000 // 1秒后过期
+	deadline := fc.Now().Add(5 * time.Second).UnixMilli()
+	deadline2 := fc.Now().UnixMilli() + 1000 // 1秒后过期
 
 	// 测试添加和获取元素
 	cache.Set("key1", 1, deadline)
@@ -28,7 +31,7 @@ func TestTimeoutCache(t *testing.T) {
 	}
 
 	// 测试过期元素
-	time.Sleep(2 * time.Second)
+	fc.Advance(2 * time.Second)
 	if _, ok := cache.Get("key2"); ok {
 		t.Error("Expected key2 to be expired")
 	}
@@ -37,13 +40,14 @@ func TestTimeoutCache(t *testing.T) {
 // 测试缓存容量限制和淘汰机制
 func TestCacheCapacity(t *testing.T) {
 	capacity := 3
-	cache := NewTimeoutCache[string, int](capacity)
+	fc := clock.NewFakeClock(time.Now())
+	cache := NewTimeoutCache[string, int](capacity, WithClock(fc))
 
 	// 添加超出容量的元素
 	for i := 0; i < capacity+2; i++ {
 		key := "key" + string(rune('0'+i))
-		cache.Set(key, i, time.Now().Add(10*time.Second).UnixMilli())
-		time.Sleep(100 * time.Millisecond) // 确保每个元素的添加时间不同
+		cache.Set(key, i, fc.Now().Add(10*time.Second).UnixMilli())
+		fc.Advance(100 * time.Millisecond) // 确保每个元素的添加时间不同
 	}
 
 	// 检查总数量是否正确
@@ -76,10 +80,11 @@ func TestGetNonExistentKey(t *testing.T) {
 
 // 测试永不过期的元素(deadline=0)
 func TestNeverExpire(t *testing.T) {
-	cache := NewTimeoutCache[string, int](5)
+	fc := clock.NewFakeClock(time.Now())
+	cache := NewTimeoutCache[string, int](5, WithClock(fc))
 	cache.Set("permanent", 100, 0) // deadline=0表示永不过期
 
-	time.Sleep(2 * time.Second)
+	fc.Advance(24 * time.Hour)
 	val, ok := cache.Get("permanent")
 	if !ok || val != 100 {
 		t.Errorf("Expected permanent key to exist with value 100, got %v", val)
@@ -155,3 +160,97 @@ func TestBulkUpdate(t *testing.T) {
 		}
 	}
 }
+
+// 测试容量已满时优先清理过期元素
+func TestEvictExpiredFirst(t *testing.T) {
+	fc := clock.NewFakeClock(time.Now())
+	cache := NewTimeoutCache[string, int](2, WithClock(fc))
+	var expired []string
+	cache.OnExpire(func(key string, value int) {
+		expired = append(expired, key)
+	})
+
+	cache.Set("permanent", 1, 0)
+	cache.SetWithTTL("short", 2, time.Millisecond)
+	fc.Advance(5 * time.Millisecond)
+	cache.Set("new", 3, 0)
+
+	if len(expired) != 1 || expired[0] != "short" {
+		t.Errorf("Expected short to expire, got %v", expired)
+	}
+	if _, ok := cache.Get("permanent"); !ok {
+		t.Error("Expected permanent key to be kept")
+	}
+	if stats := cache.Stats(); stats.Evictions != 0 {
+		t.Errorf("Expected no evictions, got %d", stats.Evictions)
+	}
+}
+
+// 测试LRU和LFU淘汰策略
+func TestEvictPolicy(t *testing.T) {
+	lru := NewTimeoutCache[string, int](2, WithEvictPolicy(EvictLRU))
+	lru.Set("a", 1, 0)
+	lru.Set("b", 2, 0)
+	lru.Get("a")
+	lru.Set("c", 3, 0) // 淘汰 b
+	if _, ok := lru.Get("b"); ok {
+		t.Error("Expected b to be evicted by LRU")
+	}
+	if _, ok := lru.Get("a"); !ok {
+		t.Error("Expected a to be kept by LRU")
+	}
+
+	lfu := NewTimeoutCache[string, int](2, WithEvictPolicy(EvictLFU))
+	lfu.Set("a", 1, 0)
+	lfu.Set("b", 2, 0)
+	lfu.Get("a")
+	lfu.Get("a")
+	lfu.Get("b")
+	lfu.Set("c", 3, 0) // 淘汰访问次数最少的 b
+	if _, ok := lfu.Get("b"); ok {
+		t.Error("Expected b to be evicted by LFU")
+	}
+	if !lfu.Delete("a") || lfu.Size() != 1 {
+		t.Errorf("Expected 1 element after delete, got %d", lfu.Size())
+	}
+	lfu.Set("d", 4, 0)
+	lfu.Set("e", 5, 0)
+	if lfu.Size() != 2 {
+		t.Errorf("Expected 2 elements, got %d", lfu.Size())
+	}
+}
+
+// 测试后台清理协程
+func TestJanitor(t *testing.T) {
+	fc := clock.NewFakeClock(time.Now())
+	cache := NewTimeoutCache[string, int](10, WithJanitor(time.Minute), WithClock(fc))
+	defer cache.Close()
+
+	done := make(chan string, 1)
+	cache.OnExpire(func(key string, value int) {
+		done <- key
+	})
+	cache.SetWithTTL("key", 1, 90*time.Second)
+
+	fc.BlockUntil(1) // 等待后台协程创建 ticker
+	fc.Advance(time.Minute)
+	fc.WaitIdle()
+	select {
+	case key := <-done:
+		t.Fatalf("Expected %s to be alive after the first sweep", key)
+	default:
+	}
+	fc.Advance(time.Minute)
+	select {
+	case key := <-done:
+		if key != "key" {
+			t.Errorf("Expected key to expire, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Janitor did not remove the expired key")
+	}
+	if cache.Size() != 0 {
+		t.Errorf("Expected empty cache, got %d", cache.Size())
+	}
+	cache.Close()
+}
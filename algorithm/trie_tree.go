@@ -1,73 +1,220 @@
 package algorithm
 
-type TrieNode struct {
-	children map[rune]*TrieNode
-	isEnd    bool // 是否是一个完整的单词
+import (
+	"slices"
+	"unicode/utf8"
+)
+
+type TrieNode[V any] struct {
+	children map[rune]*TrieNode[V]
+	isEnd    bool    // 是否是一个完整的单词
+	count    int     // 单词被插入的次数
+	value    V       // 单词关联的值
+	weight   float64 // 自动补全排序使用的权重
+	weighted bool    // 是否通过 SetWeight 设置过权重，否则使用 count
+}
+
+func newTrieNode[V any]() *TrieNode[V] {
+	return &TrieNode[V]{
+		children: make(map[rune]*TrieNode[V]),
+		isEnd:    false,
+	}
+}
+
+func (n *TrieNode[V]) score() float64 {
+	if n.weighted {
+		return n.weight
+	}
+	return float64(n.count)
 }
 
-type TrieTree struct {
-	root *TrieNode
+type TrieTree[V any] struct {
+	root *TrieNode[V]
+	size int // 单词数量
 }
 
-func NewTrieTree() *TrieTree {
-	return &TrieTree{
-		root: &TrieNode{
-			children: make(map[rune]*TrieNode),
-			isEnd:    false,
-		},
+func NewTrieTree[V any]() *TrieTree[V] {
+	return &TrieTree[V]{
+		root: newTrieNode[V](),
 	}
 }
 
-func (t *TrieTree) Insert(word string) {
+func (t *TrieTree[V]) Insert(word string) {
 	node := t.root
 	for _, char := range word { // 遍历每个字符
 		if _, exists := node.children[char]; !exists { // 如果当前字符不存在，则创建一个新的 TrieNode
-			node.children[char] = &TrieNode{
-				children: make(map[rune]*TrieNode),
-				isEnd:    false,
-			}
+			node.children[char] = newTrieNode[V]()
 		}
 		node = node.children[char]
 	}
+	if !node.isEnd {
+		t.size++
+	}
 	node.isEnd = true
+	node.count++
+}
+
+// Put 插入单词并设置关联的值
+func (t *TrieTree[V]) Put(word string, value V) {
+	t.Insert(word)
+	t.find(word).value = value
+}
+
+// Get 获取单词关联的值
+func (t *TrieTree[V]) Get(word string) (V, bool) {
+	node := t.find(word)
+	if node == nil || !node.isEnd {
+		var zero V
+		return zero, false
+	}
+	return node.value, true
+}
+
+// Count 返回单词被插入的次数，单词不存在时返回 0
+func (t *TrieTree[V]) Count(word string) int {
+	node := t.find(word)
+	if node == nil || !node.isEnd {
+		return 0
+	}
+	return node.count
+}
+
+// SetWeight 设置单词在自动补全中的权重，未设置时使用插入次数，单词不存在时返回 false
+func (t *TrieTree[V]) SetWeight(word string, weight float64) bool {
+	node := t.find(word)
+	if node == nil || !node.isEnd {
+		return false
+	}
+	node.weight = weight
+	node.weighted = true
+	return true
+}
+
+// Len 返回单词数量，重复插入的单词只计一次
+func (t *TrieTree[V]) Len() int {
+	return t.size
+}
+
+func (t *TrieTree[V]) Search(word string) bool {
+	node := t.find(word)
+	return node != nil && node.isEnd // 返回是否是一个完整的单词
 }
 
-func (t *TrieTree) Search(word string) bool {
+func (t *TrieTree[V]) StartsWith(prefix string) bool {
+	return t.find(prefix) != nil // 返回是否存在以 prefix 为前缀的单词
+}
+
+// find 返回 word 对应的节点，路径不存在时返回 nil
+func (t *TrieTree[V]) find(word string) *TrieNode[V] {
 	node := t.root
 	for _, char := range word { // 遍历每个字符
-		if _, exists := node.children[char]; !exists { // 如果当前字符不存在，则返回 false
-			return false
+		child, exists := node.children[char]
+		if !exists { // 如果当前字符不存在，则返回 nil
+			return nil
 		}
-		node = node.children[char]
+		node = child
 	}
-	return node.isEnd // 返回是否是一个完整的单词
+	return node
 }
 
-func (t *TrieTree) StartsWith(prefix string) bool {
+// KeysWithPrefix 按字典序返回以 prefix 为前缀的单词，limit <= 0 表示不限制数量
+func (t *TrieTree[V]) KeysWithPrefix(prefix string, limit int) []string {
+	node := t.find(prefix)
+	if node == nil {
+		return nil
+	}
+	var keys []string
+	t.collect(node, []rune(prefix), func(word []rune, _ *TrieNode[V]) bool {
+		keys = append(keys, string(word))
+		return limit <= 0 || len(keys) < limit
+	})
+	return keys
+}
+
+// LongestPrefixOf 返回树中作为 s 前缀的最长单词
+func (t *TrieTree[V]) LongestPrefixOf(s string) (string, bool) {
 	node := t.root
-	for _, char := range prefix { // 遍历每个字符
-		if _, exists := node.children[char]; !exists { // 如果当前字符不存在，则返回 false
+	length, found := 0, node.isEnd
+	for i := 0; i < len(s); {
+		char, size := utf8.DecodeRuneInString(s[i:])
+		child, exists := node.children[char]
+		if !exists {
+			break
+		}
+		node = child
+		i += size
+		if node.isEnd {
+			length, found = i, true
+		}
+	}
+	return s[:length], found
+}
+
+type trieCandidate struct {
+	word   string
+	weight float64
+}
+
+// Autocomplete 返回以 prefix 为前缀、权重最高的 k 个单词，权重相同时按字典序
+func (t *TrieTree[V]) Autocomplete(prefix string, k int) []string {
+	node := t.find(prefix)
+	if node == nil || k <= 0 {
+		return nil
+	}
+	topK := NewTopK(k, func(a, b trieCandidate) bool {
+		if a.weight != b.weight {
+			return a.weight < b.weight
+		}
+		return a.word > b.word // 字典序靠前的优先
+	})
+	t.collect(node, []rune(prefix), func(word []rune, n *TrieNode[V]) bool {
+		topK.Add(trieCandidate{word: string(word), weight: n.score()})
+		return true
+	})
+	candidates := topK.Items()
+	words := make([]string, len(candidates))
+	for i, c := range candidates {
+		words[i] = c.word
+	}
+	return words
+}
+
+// collect 按字典序深度优先遍历 node 下的所有单词，visit 返回 false 时停止遍历
+func (t *TrieTree[V]) collect(node *TrieNode[V], word []rune, visit func(word []rune, node *TrieNode[V]) bool) bool {
+	if node.isEnd && !visit(word, node) {
+		return false
+	}
+	chars := make([]rune, 0, len(node.children))
+	for char := range node.children {
+		chars = append(chars, char)
+	}
+	slices.Sort(chars)
+	for _, char := range chars {
+		if !t.collect(node.children[char], append(word, char), visit) {
 			return false
 		}
-		node = node.children[char]
 	}
-	return true // 返回是否存在以 prefix 为前缀的单词
+	return true
 }
 
-func (t *TrieTree) Delete(word string) {
-	t.deleteHelper(t.root, word, 0)
+func (t *TrieTree[V]) Delete(word string) {
+	t.deleteHelper(t.root, []rune(word), 0)
 }
 
-func (t *TrieTree) deleteHelper(node *TrieNode, word string, index int) bool {
+func (t *TrieTree[V]) deleteHelper(node *TrieNode[V], word []rune, index int) bool {
 	if index == len(word) { // 到达单词的末尾
 		if !node.isEnd { // 如果当前节点不是单词的终止符，说明单词不存在
 			return false // 单词不存在
 		}
 		node.isEnd = false
+		node.count = 0
+		node.value = *new(V)
+		node.weight, node.weighted = 0, false
+		t.size--
 		return len(node.children) == 0 // 如果没有子节点，返回 true 以删除该节点
 	}
 
-	char := rune(word[index])
+	char := word[index]
 	childNode, exists := node.children[char]
 	if !exists {
 		return false // 单词不存在
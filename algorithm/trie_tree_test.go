@@ -4,7 +4,7 @@ import "testing"
 
 // 测试基本插入和搜索功能
 func TestTrie_BasicOperations(t *testing.T) {
-	trie := NewTrieTree()
+	trie := NewTrieTree[int]()
 
 	// 测试初始状态
 	if trie.Search("") {
@@ -40,7 +40,7 @@ func TestTrie_BasicOperations(t *testing.T) {
 
 // 测试删除功能
 func TestTrie_DeleteOperations(t *testing.T) {
-	trie := NewTrieTree()
+	trie := NewTrieTree[int]()
 	words := []string{"apple", "app", "application", "banana", "ball", "cat"}
 
 	// 插入所有测试单词
@@ -102,7 +102,7 @@ func TestTrie_DeleteOperations(t *testing.T) {
 
 // 测试边界情况
 func TestTrie_EdgeCases(t *testing.T) {
-	trie := NewTrieTree()
+	trie := NewTrieTree[int]()
 
 	// 测试空字符串
 	trie.Insert("")
@@ -155,3 +155,83 @@ func TestTrie_EdgeCases(t *testing.T) {
 		t.Error("删除后不应再搜索到长单词")
 	}
 }
+
+// 测试非ASCII单词的删除
+func TestTrie_UnicodeDelete(t *testing.T) {
+	trie := NewTrieTree[int]()
+	trie.Insert("中国")
+	trie.Insert("中国人")
+	trie.Insert("中文")
+
+	trie.Delete("中国")
+	if trie.Search("中国") {
+		t.Error("删除后不应再搜索到中国")
+	}
+	if !trie.Search("中国人") || !trie.Search("中文") {
+		t.Error("删除中国不应影响其他单词")
+	}
+
+	trie.Delete("中国人")
+	if trie.StartsWith("中国") {
+		t.Error("所有以中国为前缀的单词删除后，前缀匹配应返回false")
+	}
+	if trie.Len() != 1 {
+		t.Errorf("期望剩余1个单词，实际为%d", trie.Len())
+	}
+}
+
+// 测试前缀枚举和最长前缀
+func TestTrie_PrefixQueries(t *testing.T) {
+	trie := NewTrieTree[string]()
+	trie.Put("app", "应用")
+	trie.Put("apple", "苹果")
+	trie.Insert("application")
+	trie.Insert("banana")
+
+	if keys := trie.KeysWithPrefix("app", 0); len(keys) != 3 || keys[0] != "app" || keys[1] != "apple" || keys[2] != "application" {
+		t.Errorf("前缀枚举结果不正确: %v", keys)
+	}
+	if keys := trie.KeysWithPrefix("app", 2); len(keys) != 2 {
+		t.Errorf("limit为2时应返回2个单词，实际为%v", keys)
+	}
+	if keys := trie.KeysWithPrefix("x", 0); len(keys) != 0 {
+		t.Errorf("不存在的前缀应返回空，实际为%v", keys)
+	}
+
+	if prefix, ok := trie.LongestPrefixOf("applesauce"); !ok || prefix != "apple" {
+		t.Errorf("最长前缀应为apple，实际为%s", prefix)
+	}
+	if _, ok := trie.LongestPrefixOf("ap"); ok {
+		t.Error("ap不应有匹配的前缀单词")
+	}
+
+	if val, ok := trie.Get("apple"); !ok || val != "苹果" {
+		t.Errorf("apple的值应为苹果，实际为%s", val)
+	}
+	if _, ok := trie.Get("appl"); ok {
+		t.Error("appl不是单词，不应有值")
+	}
+}
+
+// 测试按权重自动补全
+func TestTrie_Autocomplete(t *testing.T) {
+	trie := NewTrieTree[int]()
+	for _, word := range []string{"go", "golang", "golang", "google", "google", "google", "gopher"} {
+		trie.Insert(word)
+	}
+	if trie.Count("google") != 3 {
+		t.Errorf("google应被插入3次，实际为%d", trie.Count("google"))
+	}
+
+	if words := trie.Autocomplete("go", 2); len(words) != 2 || words[0] != "google" || words[1] != "golang" {
+		t.Errorf("按插入次数补全结果不正确: %v", words)
+	}
+
+	trie.SetWeight("gopher", 10)
+	if words := trie.Autocomplete("gop", 5); len(words) != 1 || words[0] != "gopher" {
+		t.Errorf("补全结果不正确: %v", words)
+	}
+	if words := trie.Autocomplete("go", 1); len(words) != 1 || words[0] != "gopher" {
+		t.Errorf("设置权重后补全结果不正确: %v", words)
+	}
+}
@@ -0,0 +1,242 @@
+package bulkhead
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"GoTools/clock"
+)
+
+var (
+	ErrBulkheadFull   = errors.New("bulkhead is full")
+	ErrQueueTimeout   = errors.New("bulkhead queue timeout")
+	ErrWeightTooLarge = errors.New("weight exceeds bulkhead size")
+	ErrInvalidWeight  = errors.New("weight must be positive")
+)
+
+// Order 名额空出时唤醒等待者的顺序
+type Order int
+
+const (
+	FIFO Order = iota // 先来先得，保证公平
+	LIFO              // 后来先得，过载时优先服务新请求，队列已满时淘汰等待最久的请求，避免所有请求都排队到超时
+)
+
+type waiter struct {
+	n     int64
+	ready chan struct{} // 拿到名额或被淘汰时关闭
+	err   error         // 被淘汰时为 ErrBulkheadFull
+}
+
+// Bulkhead 带权重的信号量隔离，限制对某个依赖的并发调用量，名额不足时在有界队列中等待
+type Bulkhead struct {
+	mu       sync.Mutex
+	size     int64
+	cur      int64
+	waiters  *list.List // 元素为 *waiter
+	rejected uint64
+	cfg      config
+}
+
+type config struct {
+	maxQueue     int
+	order        Order
+	queueTimeout time.Duration
+	clock        clock.Clock
+}
+
+type Option func(*config)
+
+// WithMaxQueue 设置最多排队的请求数，0 表示名额不足时直接拒绝，默认与 size 相同
+func WithMaxQueue(n int) Option {
+	return func(c *config) {
+		c.maxQueue = n
+	}
+}
+
+// WithOrder 设置唤醒顺序，默认 FIFO
+func WithOrder(order Order) Option {
+	return func(c *config) {
+		c.order = order
+	}
+}
+
+// WithQueueTimeout 设置每个请求最多排队的时间，超时返回 ErrQueueTimeout，默认只受 ctx 限制
+func WithQueueTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.queueTimeout = d
+	}
+}
+
+// WithClock 指定排队超时使用的时间来源
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+func New(size int64, opts ...Option) *Bulkhead {
+	cfg := config{maxQueue: int(size), clock: clock.New()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Bulkhead{
+		size:    size,
+		waiters: list.New(),
+		cfg:     cfg,
+	}
+}
+
+// TryAcquire 不等待地获取权重为 n 的名额，有请求在排队时也会失败，n 不大于 0 时返回 false
+func (b *Bulkhead) TryAcquire(n int64) bool {
+	if n <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n <= b.size && b.size-b.cur >= n && b.waiters.Len() == 0 {
+		b.cur += n
+		return true
+	}
+	b.rejected++
+	return false
+}
+
+// Acquire 获取权重为 n 的名额，名额不足时排队等待，直到拿到名额、ctx 结束或排队超时
+// 队列已满时返回 ErrBulkheadFull，LIFO 模式下则淘汰等待最久的请求，被淘汰的请求返回 ErrBulkheadFull
+// n 不大于 0 时返回 ErrInvalidWeight，超过 size 时返回 ErrWeightTooLarge
+func (b *Bulkhead) Acquire(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return ErrInvalidWeight
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	if n > b.size {
+		b.rejected++
+		b.mu.Unlock()
+		return ErrWeightTooLarge
+	}
+	if b.size-b.cur >= n && b.waiters.Len() == 0 {
+		b.cur += n
+		b.mu.Unlock()
+		return nil
+	}
+	if b.waiters.Len() >= b.cfg.maxQueue {
+		if b.cfg.order != LIFO || b.waiters.Len() == 0 {
+			b.rejected++
+			b.mu.Unlock()
+			return ErrBulkheadFull
+		}
+		b.evict(b.waiters.Front())
+	}
+	w := &waiter{n: n, ready: make(chan struct{})}
+	elem := b.waiters.PushBack(w)
+	b.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if b.cfg.queueTimeout > 0 {
+		timer := b.cfg.clock.NewTimer(b.cfg.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		return w.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrQueueTimeout
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-w.ready:
+		if w.err != nil {
+			return w.err
+		}
+		// 放弃的同时拿到了名额，归还给后面的等待者
+		b.cur -= n
+	default:
+		b.waiters.Remove(elem)
+		b.rejected++
+	}
+	b.notifyWaiters()
+	return err
+}
+
+// Release 归还权重为 n 的名额
+func (b *Bulkhead) Release(n int64) {
+	if n <= 0 {
+		panic("bulkhead: release weight must be positive")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cur -= n
+	if b.cur < 0 {
+		panic("bulkhead: released more than held")
+	}
+	b.notifyWaiters()
+}
+
+// Execute 获取权重为 n 的名额后执行 fn，结束后归还
+func (b *Bulkhead) Execute(ctx context.Context, n int64, fn func(ctx context.Context) error) error {
+	if err := b.Acquire(ctx, n); err != nil {
+		return err
+	}
+	defer b.Release(n)
+	return fn(ctx)
+}
+
+// Active 返回正在使用的权重
+func (b *Bulkhead) Active() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cur
+}
+
+// Queued 返回正在排队的请求数
+func (b *Bulkhead) Queued() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.waiters.Len()
+}
+
+// Rejected 返回没有拿到名额的请求总数，包括队列已满、被淘汰、排队超时和 ctx 结束
+func (b *Bulkhead) Rejected() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rejected
+}
+
+// evict 淘汰等待者，调用方需持有锁
+func (b *Bulkhead) evict(elem *list.Element) {
+	w := b.waiters.Remove(elem).(*waiter)
+	w.err = ErrBulkheadFull
+	b.rejected++
+	close(w.ready)
+}
+
+// notifyWaiters 按顺序唤醒名额足够的等待者，遇到名额不够的等待者就停下，避免大权重的请求饿死，调用方需持有锁
+func (b *Bulkhead) notifyWaiters() {
+	for b.waiters.Len() > 0 {
+		next := b.waiters.Front()
+		if b.cfg.order == LIFO {
+			next = b.waiters.Back()
+		}
+		w := next.Value.(*waiter)
+		if b.size-b.cur < w.n {
+			return
+		}
+		b.cur += w.n
+		b.waiters.Remove(next)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,159 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"GoTools/clock"
+)
+
+// enqueue 在后台获取名额，等到请求进入队列后返回结果通道
+func enqueue(b *Bulkhead, ctx context.Context, n int64) <-chan error {
+	queued, rejected := b.Queued(), b.Rejected()
+	result := make(chan error, 1)
+	go func() { result <- b.Acquire(ctx, n) }()
+	// LIFO 模式下淘汰最早的请求后队列长度不变，但拒绝数会增加
+	for b.Queued() == queued && b.Rejected() == rejected {
+		time.Sleep(time.Millisecond)
+	}
+	return result
+}
+
+func TestBulkheadFIFO(t *testing.T) {
+	b := New(3, WithMaxQueue(2))
+	ctx := context.Background()
+
+	if err := b.Acquire(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !b.TryAcquire(1) || b.TryAcquire(1) {
+		t.Fatalf("名额为3时应只能再获取1个")
+	}
+	big := enqueue(b, ctx, 2)
+	small := enqueue(b, ctx, 1)
+	if err := b.Acquire(ctx, 1); !errors.Is(err, ErrBulkheadFull) {
+		t.Errorf("队列已满时期望 ErrBulkheadFull，实际 %v", err)
+	}
+	if err := b.Acquire(ctx, 4); !errors.Is(err, ErrWeightTooLarge) {
+		t.Errorf("权重超过总量时期望 ErrWeightTooLarge，实际 %v", err)
+	}
+
+	// 空出1个名额时排在前面的大请求仍然不够，后面的小请求也不能插队
+	b.Release(1)
+	select {
+	case <-small:
+		t.Fatalf("FIFO 模式下小请求不应插队")
+	case <-time.After(10 * time.Millisecond):
+	}
+	b.Release(2)
+	if err := <-big; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-small; err != nil {
+		t.Fatal(err)
+	}
+	if b.Active() != 3 || b.Queued() != 0 || b.Rejected() != 3 {
+		t.Errorf("期望使用3、排队0、拒绝3，实际 %d、%d、%d", b.Active(), b.Queued(), b.Rejected())
+	}
+}
+
+func TestBulkheadLIFO(t *testing.T) {
+	b := New(1, WithMaxQueue(2), WithOrder(LIFO))
+	ctx := context.Background()
+	b.TryAcquire(1)
+
+	oldest := enqueue(b, ctx, 1)
+	older := enqueue(b, ctx, 1)
+	newest := enqueue(b, ctx, 1)
+	// 队列已满时淘汰等待最久的请求
+	if err := <-oldest; !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("期望淘汰最早的请求，实际 %v", err)
+	}
+	b.Release(1)
+	if err := <-newest; err != nil {
+		t.Fatalf("LIFO 模式下应先唤醒最新的请求: %v", err)
+	}
+	if b.Queued() != 1 {
+		t.Errorf("期望还有1个请求排队，实际 %d", b.Queued())
+	}
+	b.Release(1)
+	if err := <-older; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkheadTimeout(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	b := New(1, WithQueueTimeout(time.Second), WithClock(fc))
+	b.TryAcquire(1)
+
+	timedOut := enqueue(b, context.Background(), 1)
+	fc.BlockUntil(1)
+	fc.Advance(time.Second)
+	if err := <-timedOut; !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("期望 ErrQueueTimeout，实际 %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := enqueue(b, ctx, 1)
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Errorf("期望 context.Canceled，实际 %v", err)
+	}
+	if b.Queued() != 0 || b.Rejected() != 2 {
+		t.Errorf("放弃的请求应移出队列并计入拒绝数，实际排队 %d，拒绝 %d", b.Queued(), b.Rejected())
+	}
+
+	b.Release(1)
+	calls := 0
+	err := b.Execute(context.Background(), 1, func(ctx context.Context) error {
+		calls++
+		if b.Active() != 1 {
+			t.Errorf("执行期间应占用名额")
+		}
+		return nil
+	})
+	if err != nil || calls != 1 || b.Active() != 0 {
+		t.Errorf("Execute 结束后应归还名额，实际 %v %d", err, b.Active())
+	}
+}
+
+// 权重不大于 0 或超过总量的请求直接失败，不能改变已使用的名额
+func TestBulkheadInvalidWeight(t *testing.T) {
+	b := New(2, WithMaxQueue(2))
+	ctx := context.Background()
+
+	for _, n := range []int64{0, -3} {
+		if b.TryAcquire(n) {
+			t.Errorf("TryAcquire(%d) 应返回 false", n)
+		}
+		if err := b.Acquire(ctx, n); !errors.Is(err, ErrInvalidWeight) {
+			t.Errorf("Acquire(%d) 期望 ErrInvalidWeight，实际 %v", n, err)
+		}
+	}
+	if b.TryAcquire(3) {
+		t.Error("权重超过总量时 TryAcquire 应返回 false")
+	}
+	if err := b.Acquire(ctx, 3); !errors.Is(err, ErrWeightTooLarge) {
+		t.Errorf("权重超过总量时期望 ErrWeightTooLarge，实际 %v", err)
+	}
+	if b.Active() != 0 || b.Queued() != 0 {
+		t.Errorf("不合法的请求不应占用名额或排队，实际使用 %d、排队 %d", b.Active(), b.Queued())
+	}
+	if !b.TryAcquire(2) || b.TryAcquire(1) {
+		t.Error("总量应保持为2")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("归还负数权重应 panic")
+			}
+		}()
+		b.Release(-1)
+	}()
+	if b.Active() != 2 {
+		t.Errorf("归还负数权重不应改变已使用的名额，实际 %d", b.Active())
+	}
+}
@@ -0,0 +1,370 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"GoTools/clock"
+)
+
+var (
+	ErrOpenState       = errors.New("circuit breaker is open")
+	ErrTooManyRequests = errors.New("too many requests in half-open state")
+)
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed   State = iota // 正常放行，统计失败情况
+	StateOpen                  // 熔断中，拒绝所有请求
+	StateHalfOpen              // 熔断超时后放行少量探测请求，全部成功则恢复
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Counts 滑动窗口内的请求统计
+type Counts struct {
+	Requests            uint64
+	Successes           uint64
+	Failures            uint64
+	ConsecutiveFailures uint64 // 最近连续失败的次数，不受窗口限制
+}
+
+// ErrorRatio 返回失败比例，没有请求时为 0
+func (c Counts) ErrorRatio() float64 {
+	if c.Requests == 0 {
+		return 0
+	}
+	return float64(c.Failures) / float64(c.Requests)
+}
+
+type bucket struct {
+	successes uint64
+	failures  uint64
+}
+
+// CircuitBreaker 熔断器，在滑动窗口内的失败次数或失败比例超过阈值时熔断，熔断超时后进入半开状态探测
+type CircuitBreaker struct {
+	name string
+	mu   sync.Mutex
+
+	state      State
+	generation uint64    // 每次状态变化加一，用于忽略上一个状态中发出的请求的结果
+	openedAt   time.Time // 进入熔断的时间
+
+	buckets     []bucket
+	bucketSize  time.Duration
+	bucketStart time.Time // 当前桶的开始时间
+	current     int       // 当前桶的下标
+	consecutive uint64
+
+	probing   int // 半开状态中正在执行的探测请求数
+	probeSucc int // 半开状态中成功的探测请求数
+
+	cfg     config
+	pending []stateChange // 待通知的状态变化，释放锁后通知
+}
+
+type stateChange struct {
+	from, to State
+}
+
+type config struct {
+	window           time.Duration
+	bucketNum        int
+	failureThreshold uint64
+	errorRatio       float64
+	minRequests      uint64
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	onStateChange    func(name string, from, to State)
+	isSuccessful     func(err error) bool
+	clock            clock.Clock
+}
+
+type Option func(*config)
+
+// WithWindow 设置统计窗口长度和桶的数量，默认 10 秒、10 个桶
+func WithWindow(window time.Duration, buckets int) Option {
+	return func(c *config) {
+		c.window = window
+		c.bucketNum = buckets
+	}
+}
+
+// WithFailureThreshold 窗口内失败次数达到 n 时熔断，未设置任何阈值时默认为 5
+func WithFailureThreshold(n uint64) Option {
+	return func(c *config) {
+		c.failureThreshold = n
+	}
+}
+
+// WithErrorRatio 窗口内请求数不少于 minRequests 且失败比例达到 ratio 时熔断
+func WithErrorRatio(ratio float64, minRequests uint64) Option {
+	return func(c *config) {
+		c.errorRatio = ratio
+		c.minRequests = minRequests
+	}
+}
+
+// WithOpenTimeout 设置熔断持续时间，之后进入半开状态，默认 5 秒
+func WithOpenTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.openTimeout = d
+	}
+}
+
+// WithHalfOpenProbes 设置半开状态放行的探测请求数，全部成功后恢复，默认 1
+func WithHalfOpenProbes(n int) Option {
+	return func(c *config) {
+		c.halfOpenProbes = n
+	}
+}
+
+// WithOnStateChange 设置状态变化的回调，回调在释放锁之后同步执行
+func WithOnStateChange(fn func(name string, from, to State)) Option {
+	return func(c *config) {
+		c.onStateChange = fn
+	}
+}
+
+// WithIsSuccessful 设置 Execute 判断错误是否计为失败的方式，默认只有 nil 计为成功
+// 例如参数错误等客户端错误不应触发熔断，可以在这里返回 true
+func WithIsSuccessful(fn func(err error) bool) Option {
+	return func(c *config) {
+		c.isSuccessful = fn
+	}
+}
+
+// WithClock 指定时间来源，测试中可传入 clock.FakeClock
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+func New(name string, opts ...Option) *CircuitBreaker {
+	cfg := config{
+		window:         10 * time.Second,
+		bucketNum:      10,
+		openTimeout:    5 * time.Second,
+		halfOpenProbes: 1,
+		isSuccessful:   func(err error) bool { return err == nil },
+		clock:          clock.New(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.failureThreshold == 0 && cfg.errorRatio <= 0 {
+		cfg.failureThreshold = 5
+	}
+	cfg.bucketNum = max(cfg.bucketNum, 1)
+	cfg.halfOpenProbes = max(cfg.halfOpenProbes, 1)
+	return &CircuitBreaker{
+		name:        name,
+		buckets:     make([]bucket, cfg.bucketNum),
+		bucketSize:  max(cfg.window/time.Duration(cfg.bucketNum), time.Nanosecond),
+		bucketStart: cfg.clock.Now(),
+		cfg:         cfg,
+	}
+}
+
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
+// State 返回当前状态，熔断超时后会返回半开状态
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	cb.refresh(cb.cfg.clock.Now())
+	state := cb.state
+	cb.unlock()
+	return state
+}
+
+// Counts 返回滑动窗口内的统计
+func (cb *CircuitBreaker) Counts() Counts {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.rotate(cb.cfg.clock.Now())
+	return cb.counts()
+}
+
+// Allow 判断是否放行请求，放行时返回的 done 必须在请求结束后调用一次，报告请求是否成功
+// 熔断中返回 ErrOpenState，半开状态的探测名额用完时返回 ErrTooManyRequests
+func (cb *CircuitBreaker) Allow() (done func(success bool), err error) {
+	cb.mu.Lock()
+	now := cb.cfg.clock.Now()
+	cb.refresh(now)
+	switch cb.state {
+	case StateOpen:
+		cb.unlock()
+		return nil, ErrOpenState
+	case StateHalfOpen:
+		if cb.probing+cb.probeSucc >= cb.cfg.halfOpenProbes {
+			cb.unlock()
+			return nil, ErrTooManyRequests
+		}
+		cb.probing++
+	}
+	generation := cb.generation
+	cb.unlock()
+
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() {
+			cb.done(generation, success)
+		})
+	}, nil
+}
+
+// Execute 在熔断器保护下执行 fn，fn 返回的错误按 WithIsSuccessful 判断是否计为失败，panic 计为失败并继续向上抛出
+func (cb *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done, err := cb.Allow()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			done(false)
+			panic(r)
+		}
+	}()
+	err = fn(ctx)
+	done(cb.cfg.isSuccessful(err))
+	return err
+}
+
+// Reset 手动恢复到关闭状态并清空统计
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	cb.setState(StateClosed, cb.cfg.clock.Now())
+	cb.unlock()
+}
+
+func (cb *CircuitBreaker) done(generation uint64, success bool) {
+	cb.mu.Lock()
+	defer cb.unlock()
+	now := cb.cfg.clock.Now()
+	cb.refresh(now)
+	if generation != cb.generation {
+		return // 请求发出后状态已经变化，结果不再有参考意义
+	}
+
+	switch cb.state {
+	case StateClosed:
+		cb.rotate(now)
+		if success {
+			cb.buckets[cb.current].successes++
+			cb.consecutive = 0
+			return
+		}
+		cb.buckets[cb.current].failures++
+		cb.consecutive++
+		if cb.shouldTrip(cb.counts()) {
+			cb.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		cb.probing--
+		if !success {
+			cb.setState(StateOpen, now)
+			return
+		}
+		cb.probeSucc++
+		if cb.probeSucc >= cb.cfg.halfOpenProbes {
+			cb.setState(StateClosed, now)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) shouldTrip(c Counts) bool {
+	if cb.cfg.failureThreshold > 0 && c.Failures >= cb.cfg.failureThreshold {
+		return true
+	}
+	return cb.cfg.errorRatio > 0 && c.Requests >= cb.cfg.minRequests && c.ErrorRatio() >= cb.cfg.errorRatio
+}
+
+// refresh 熔断超时后进入半开状态，调用方需持有锁
+func (cb *CircuitBreaker) refresh(now time.Time) {
+	if cb.state == StateOpen && now.Sub(cb.openedAt) >= cb.cfg.openTimeout {
+		cb.setState(StateHalfOpen, now)
+	}
+}
+
+// setState 切换状态并重置对应的统计，调用方需持有锁
+func (cb *CircuitBreaker) setState(state State, now time.Time) {
+	if cb.state == state && state != StateClosed {
+		return
+	}
+	from := cb.state
+	cb.state = state
+	cb.generation++
+	cb.probing, cb.probeSucc = 0, 0
+	switch state {
+	case StateClosed:
+		clear(cb.buckets)
+		cb.bucketStart, cb.current = now, 0
+		cb.consecutive = 0
+	case StateOpen:
+		cb.openedAt = now
+	}
+	if from != state {
+		cb.pending = append(cb.pending, stateChange{from: from, to: state})
+	}
+}
+
+// unlock 释放锁并通知期间发生的状态变化
+func (cb *CircuitBreaker) unlock() {
+	changes := cb.pending
+	cb.pending = nil
+	onChange := cb.cfg.onStateChange
+	cb.mu.Unlock()
+	if onChange == nil {
+		return
+	}
+	for _, c := range changes {
+		onChange(cb.name, c.from, c.to)
+	}
+}
+
+// rotate 把窗口滑动到 now，清空已经移出窗口的桶，调用方需持有锁
+func (cb *CircuitBreaker) rotate(now time.Time) {
+	elapsed := now.Sub(cb.bucketStart)
+	if elapsed < cb.bucketSize {
+		return
+	}
+	steps := int(elapsed / cb.bucketSize)
+	for i := 0; i < min(steps, len(cb.buckets)); i++ {
+		cb.current = (cb.current + 1) % len(cb.buckets)
+		cb.buckets[cb.current] = bucket{}
+	}
+	cb.bucketStart = cb.bucketStart.Add(time.Duration(steps) * cb.bucketSize)
+}
+
+// counts 汇总所有桶，调用方需持有锁
+func (cb *CircuitBreaker) counts() Counts {
+	c := Counts{ConsecutiveFailures: cb.consecutive}
+	for _, b := range cb.buckets {
+		c.Successes += b.successes
+		c.Failures += b.failures
+	}
+	c.Requests = c.Successes + c.Failures
+	return c
+}
@@ -0,0 +1,197 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"GoTools/clock"
+	"GoTools/loadbalance"
+)
+
+var errBackend = errors.New("backend error")
+
+func report(t *testing.T, cb *CircuitBreaker, results ...bool) {
+	t.Helper()
+	for _, success := range results {
+		done, err := cb.Allow()
+		if err != nil {
+			t.Fatalf("期望放行，实际 %v", err)
+		}
+		done(success)
+	}
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	var changes []string
+	cb := New("backend", WithClock(fc), WithFailureThreshold(3), WithOpenTimeout(time.Second), WithHalfOpenProbes(2),
+		WithOnStateChange(func(name string, from, to State) {
+			changes = append(changes, name+":"+from.String()+"->"+to.String())
+		}))
+
+	report(t, cb, false, true, false)
+	if cb.State() != StateClosed {
+		t.Fatalf("失败次数未达到阈值时应保持关闭")
+	}
+	report(t, cb, false)
+	if cb.State() != StateOpen {
+		t.Fatalf("窗口内失败3次应熔断，实际 %v", cb.State())
+	}
+	if _, err := cb.Allow(); !errors.Is(err, ErrOpenState) {
+		t.Errorf("熔断中期望 ErrOpenState，实际 %v", err)
+	}
+
+	fc.Advance(time.Second)
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("熔断超时后应进入半开状态，实际 %v", cb.State())
+	}
+	done1, _ := cb.Allow()
+	done2, err := cb.Allow()
+	if err != nil {
+		t.Fatalf("半开状态应放行2个探测请求: %v", err)
+	}
+	if _, err := cb.Allow(); !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("探测名额用完时期望 ErrTooManyRequests，实际 %v", err)
+	}
+	done1(true)
+	done1(false) // 重复调用无效
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("探测请求未全部成功前应保持半开")
+	}
+	done2(true)
+	if cb.State() != StateClosed || cb.Counts().Requests != 0 {
+		t.Fatalf("探测全部成功后应恢复并清空统计，实际 %v %+v", cb.State(), cb.Counts())
+	}
+
+	want := []string{"backend:closed->open", "backend:open->half-open", "backend:half-open->closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("状态变化通知错误: %v", changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("第%d次状态变化期望 %s，实际 %s", i, want[i], changes[i])
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	cb := New("backend", WithClock(fc), WithFailureThreshold(1), WithOpenTimeout(time.Second))
+
+	slow, _ := cb.Allow() // 熔断前发出的请求
+	report(t, cb, false)
+	fc.Advance(time.Second)
+	report(t, cb, false)
+	if cb.State() != StateOpen {
+		t.Fatalf("探测失败应重新熔断，实际 %v", cb.State())
+	}
+
+	fc.Advance(time.Second)
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("期望半开状态，实际 %v", cb.State())
+	}
+	slow(true) // 上一个状态的结果不计入探测
+	if cb.State() != StateHalfOpen {
+		t.Errorf("过期的结果不应影响状态，实际 %v", cb.State())
+	}
+	cb.Reset()
+	if cb.State() != StateClosed {
+		t.Errorf("Reset 后应恢复关闭，实际 %v", cb.State())
+	}
+}
+
+func TestCircuitBreakerErrorRatio(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	cb := New("backend", WithClock(fc), WithWindow(10*time.Second, 10), WithErrorRatio(0.5, 4))
+
+	report(t, cb, false, false, false)
+	if cb.State() != StateClosed {
+		t.Fatalf("请求数不足时不应熔断")
+	}
+	// 旧的失败移出窗口后重新统计
+	fc.Advance(10 * time.Second)
+	if c := cb.Counts(); c.Requests != 0 || c.ConsecutiveFailures != 3 {
+		t.Fatalf("窗口滑动后统计错误: %+v", c)
+	}
+	report(t, cb, true, true, false)
+	fc.Advance(5 * time.Second)
+	report(t, cb, true)
+	if c := cb.Counts(); c.Requests != 4 || c.ErrorRatio() != 0.25 || c.ConsecutiveFailures != 0 {
+		t.Fatalf("统计错误: %+v", c)
+	}
+	report(t, cb, false)
+	if cb.State() != StateClosed {
+		t.Fatalf("失败比例 40%% 不应熔断")
+	}
+	report(t, cb, false)
+	if cb.State() != StateOpen {
+		t.Errorf("失败比例达到 50%% 应熔断，实际 %v", cb.State())
+	}
+}
+
+func TestCircuitBreakerExecute(t *testing.T) {
+	errBadRequest := errors.New("bad request")
+	cb := New("backend", WithFailureThreshold(2), WithIsSuccessful(func(err error) bool {
+		return err == nil || errors.Is(err, errBadRequest)
+	}))
+	ctx := context.Background()
+
+	if err := cb.Execute(ctx, func(ctx context.Context) error { return errBadRequest }); !errors.Is(err, errBadRequest) {
+		t.Errorf("应返回 fn 的错误，实际 %v", err)
+	}
+	if cb.Counts().Failures != 0 {
+		t.Errorf("客户端错误不应计为失败")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("panic 应继续向上抛出")
+			}
+		}()
+		_ = cb.Execute(ctx, func(ctx context.Context) error { panic("boom") })
+	}()
+	_ = cb.Execute(ctx, func(ctx context.Context) error { return errBackend })
+	if err := cb.Execute(ctx, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrOpenState) {
+		t.Errorf("panic 和错误各计一次失败后应熔断，实际 %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := New("other").Execute(cancelled, func(ctx context.Context) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("期望 context.Canceled，实际 %v", err)
+	}
+}
+
+func TestGroupSelect(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	g := NewGroup(WithClock(fc), WithFailureThreshold(1), WithOpenTimeout(time.Second))
+	endpoints := []string{"a", "b"}
+	sampler := loadbalance.NewMinimumConcurrencySampler(endpoints, make([]int64, len(endpoints)))
+
+	done, _ := g.Allow("a")
+	done(false)
+	if available := g.Available(endpoints); len(available) != 1 || available[0] != "b" {
+		t.Fatalf("熔断的端点应被过滤，实际 %v", available)
+	}
+	for i := 0; i < 10; i++ {
+		endpoint, done, err := g.Select(sampler.Sample, len(endpoints)*4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if endpoint != "b" {
+			t.Fatalf("应跳过熔断的端点，实际 %s", endpoint)
+		}
+		done(true)
+	}
+
+	_ = g.Execute(context.Background(), "b", func(ctx context.Context) error { return errBackend })
+	if _, _, err := g.Select(sampler.Sample, 4); !errors.Is(err, ErrOpenState) {
+		t.Errorf("所有端点熔断时期望 ErrOpenState，实际 %v", err)
+	}
+	fc.Advance(time.Second)
+	if _, _, err := g.Select(sampler.Sample, 4); err != nil {
+		t.Errorf("熔断超时后应放行探测请求: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package circuitbreaker
+
+import (
+	"context"
+	"sync"
+)
+
+// Group 按名字（例如负载均衡的端点）管理一组配置相同的熔断器，熔断器在第一次使用时创建
+type Group struct {
+	mu       sync.RWMutex
+	opts     []Option
+	breakers map[string]*CircuitBreaker
+}
+
+func NewGroup(opts ...Option) *Group {
+	return &Group{
+		opts:     opts,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+// Get 返回 name 对应的熔断器，不存在时创建
+func (g *Group) Get(name string) *CircuitBreaker {
+	g.mu.RLock()
+	cb, ok := g.breakers[name]
+	g.mu.RUnlock()
+	if ok {
+		return cb
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if cb, ok = g.breakers[name]; !ok {
+		cb = New(name, g.opts...)
+		g.breakers[name] = cb
+	}
+	return cb
+}
+
+// Remove 删除 name 对应的熔断器，端点下线时调用
+func (g *Group) Remove(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.breakers, name)
+}
+
+// Allow 判断是否放行发往 name 的请求，用法同 CircuitBreaker.Allow
+func (g *Group) Allow(name string) (done func(success bool), err error) {
+	return g.Get(name).Allow()
+}
+
+// Execute 在 name 对应的熔断器保护下执行 fn
+func (g *Group) Execute(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	return g.Get(name).Execute(ctx, fn)
+}
+
+// Available 过滤掉处于熔断状态的端点，不占用半开状态的探测名额，可用于构造负载均衡的候选列表
+func (g *Group) Available(names []string) []string {
+	available := make([]string, 0, len(names))
+	for _, name := range names {
+		if g.Get(name).State() != StateOpen {
+			available = append(available, name)
+		}
+	}
+	return available
+}
+
+// Select 用 pick（例如 loadbalance 采样器的 Sample）选择端点，跳过熔断器不放行的端点，最多尝试 maxTries 次
+// 成功时返回端点和 done，请求结束后必须调用 done；全部被拒绝时返回最后一次的错误
+func (g *Group) Select(pick func() string, maxTries int) (endpoint string, done func(success bool), err error) {
+	err = ErrOpenState
+	for i := 0; i < max(maxTries, 1); i++ {
+		endpoint = pick()
+		if endpoint == "" {
+			break
+		}
+		if done, err = g.Allow(endpoint); err == nil {
+			return endpoint, done, nil
+		}
+	}
+	return "", nil, err
+}
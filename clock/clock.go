@@ -0,0 +1,246 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 时间来源的抽象，生产环境使用 New 返回的真实时钟，测试中使用 FakeClock 手动推进时间
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer 与 time.Timer 行为一致的定时器
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker 与 time.Ticker 行为一致的周期定时器
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+type realClock struct{}
+
+// New 返回基于 time 包的真实时钟
+func New() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time                   { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration  { return time.Since(t) }
+func (realClock) NewTimer(d time.Duration) Timer   { return &realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker { return &realTicker{time.NewTicker(d)} }
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// FakeClock 手动推进的时钟，只有调用 Advance 或 Set 时时间才会变化并触发到期的定时器
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer // 所有未触发的定时器和未停止的周期定时器
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	c.schedule(t, d)
+	return t
+}
+
+// NewTicker 创建周期定时器，d 必须大于 0
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), period: d}
+	c.schedule(t, d)
+	return &fakeTicker{t}
+}
+
+// Advance 把时间向前推进 d，并按到期顺序触发期间到期的定时器
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set 把时间设置为 t，早于当前时间时忽略
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.Before(c.now) {
+		return
+	}
+	for {
+		next := c.nextTimer()
+		if next == nil || next.deadline.After(t) {
+			break
+		}
+		c.now = next.deadline // 依次触发，保证回调看到的时间正确
+		c.fire(next)
+	}
+	c.now = t
+}
+
+// BlockUntil 阻塞直到至少有 n 个等待中的定时器，用于在推进时间前确认被测代码已经开始等待
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// WaitIdle 阻塞直到所有周期定时器已触发的时间都被读走，
+// 用于逐个推进 tick 时确认上一个 tick 已被消费，避免因通道已满被丢弃
+func (c *FakeClock) WaitIdle() {
+	for {
+		c.mu.Lock()
+		idle := true
+		for _, t := range c.timers {
+			if t.period > 0 && len(t.c) > 0 {
+				idle = false
+				break
+			}
+		}
+		c.mu.Unlock()
+		if idle {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Timers 返回等待中的定时器数量
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// nextTimer 返回最早到期的定时器，调用方需持有锁
+func (c *FakeClock) nextTimer() *fakeTimer {
+	if len(c.timers) == 0 {
+		return nil
+	}
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+	return c.timers[0]
+}
+
+// fire 触发定时器，周期定时器会重新计算下一次触发时间，调用方需持有锁
+func (c *FakeClock) fire(t *fakeTimer) {
+	if t.period > 0 {
+		t.deadline = t.deadline.Add(t.period)
+	} else {
+		c.remove(t)
+	}
+	select {
+	case t.c <- c.now:
+	default: // 与 time.Timer 一致，通道已满时丢弃
+	}
+}
+
+func (c *FakeClock) schedule(t *fakeTimer, d time.Duration) {
+	t.deadline = c.now.Add(d)
+	if d <= 0 {
+		c.fire(t)
+		return
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+}
+
+// remove 移除定时器，返回其是否处于等待状态，调用方需持有锁
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+	period   time.Duration // 大于 0 表示周期定时器
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.clock.remove(t)
+	t.clock.schedule(t, d)
+	return active
+}
+
+type fakeTicker struct {
+	t *fakeTimer
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.t.c }
+
+func (t *fakeTicker) Stop() {
+	t.t.Stop()
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	c := t.t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(t.t)
+	t.t.period = d
+	c.schedule(t.t, d)
+}
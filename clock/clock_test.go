@@ -0,0 +1,109 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockTimer(t *testing.T) {
+	start := time.Unix(1000, 0)
+	c := NewFakeClock(start)
+
+	t1 := c.NewTimer(time.Second)
+	t2 := c.NewTimer(3 * time.Second)
+	if c.Timers() != 2 {
+		t.Fatalf("Expected 2 timers, got %d", c.Timers())
+	}
+
+	c.Advance(2 * time.Second)
+	select {
+	case now := <-t1.C():
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("Expected timer to fire at its deadline, got %v", now)
+		}
+	default:
+		t.Fatal("Expected t1 to fire")
+	}
+	select {
+	case <-t2.C():
+		t.Fatal("t2 must not fire yet")
+	default:
+	}
+
+	if !t2.Stop() || t2.Stop() {
+		t.Error("Expected t2 to be stopped exactly once")
+	}
+	c.Advance(time.Hour)
+	select {
+	case <-t2.C():
+		t.Fatal("Stopped timer must not fire")
+	default:
+	}
+
+	t2.Reset(time.Second)
+	c.Advance(time.Second)
+	if _, ok := <-t2.C(); !ok {
+		t.Error("Expected reset timer to fire")
+	}
+	if !c.Now().Equal(start.Add(time.Hour + 3*time.Second)) {
+		t.Errorf("Unexpected current time %v", c.Now())
+	}
+}
+
+func TestFakeClockBlockUntil(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	done := make(chan struct{})
+	go func() {
+		<-c.NewTimer(time.Minute).C()
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected goroutine to wake up after Advance")
+	}
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	c := NewFakeClock(time.Unix(0, 0))
+	ticker := c.NewTicker(time.Second)
+
+	ticks := 0
+	for i := 0; i < 3; i++ {
+		c.Advance(time.Second)
+		<-ticker.C()
+		ticks++
+	}
+	c.WaitIdle()
+
+	// 通道已满时多余的 tick 会被丢弃
+	c.Advance(5 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected extra ticks to be dropped")
+	default:
+	}
+
+	ticker.Reset(time.Minute)
+	c.Advance(time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("Reset ticker must wait for the new interval")
+	default:
+	}
+
+	ticker.Stop()
+	c.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Fatal("Stopped ticker must not fire")
+	default:
+	}
+	if ticks != 3 || c.Timers() != 0 {
+		t.Errorf("Unexpected state: %d ticks, %d timers", ticks, c.Timers())
+	}
+}
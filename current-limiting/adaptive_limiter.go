@@ -0,0 +1,326 @@
+package current_limiting
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"GoTools/clock"
+)
+
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+// Sample 一次请求的观测结果
+type Sample struct {
+	RTT      time.Duration // 请求耗时
+	Inflight int           // 请求开始时的并发数（包含自己）
+	Dropped  bool          // 请求失败或超时，视为过载信号
+}
+
+// LimitAlgorithm 根据观测结果计算新的并发上限，由 AdaptiveLimiter 串行调用，实现无需加锁
+type LimitAlgorithm interface {
+	Update(limit float64, sample Sample) float64
+}
+
+// AIMD 加性增、乘性减：没有过载时每次加 1，失败或超时时按比例缩小
+// 字段为 0 时使用默认值，零值可以直接使用，MaxLimit 为 0 表示不限制
+type AIMD struct {
+	MinLimit     int
+	MaxLimit     int
+	BackoffRatio float64       // 过载时的缩小比例，默认 0.9
+	Timeout      time.Duration // 耗时超过该值视为过载，0 表示不按耗时判断
+}
+
+func NewAIMD(minLimit, maxLimit int) *AIMD {
+	return &AIMD{MinLimit: minLimit, MaxLimit: maxLimit, BackoffRatio: 0.9}
+}
+
+func (a *AIMD) Update(limit float64, s Sample) float64 {
+	ratio := a.BackoffRatio
+	if ratio <= 0 {
+		ratio = 0.9
+	}
+	if s.Dropped || (a.Timeout > 0 && s.RTT > a.Timeout) {
+		limit *= ratio
+	} else if s.Inflight*2 >= int(limit) {
+		limit++ // 并发数远低于上限时说明上限不是瓶颈，不再增长
+	}
+	return clampLimit(limit, a.MinLimit, a.MaxLimit)
+}
+
+// Vegas 参照 TCP Vegas，用最小耗时估计无排队时的耗时，根据 limit*(1-minRTT/rtt) 估算排队长度
+// 排队少于 alpha 时增大上限，多于 beta 时减小上限
+type Vegas struct {
+	MinLimit int
+	MaxLimit int
+	minRTT   time.Duration // 观测到的最小耗时，近似无排队时的耗时
+}
+
+func NewVegas(minLimit, maxLimit int) *Vegas {
+	return &Vegas{MinLimit: minLimit, MaxLimit: maxLimit}
+}
+
+func (v *Vegas) Update(limit float64, s Sample) float64 {
+	if s.RTT <= 0 {
+		return limit
+	}
+	if v.minRTT == 0 || s.RTT < v.minRTT {
+		v.minRTT = s.RTT
+	}
+	step := math.Max(1, math.Log10(limit)) // 上限越大步长越大
+	if s.Dropped {
+		return clampLimit(limit-step, v.MinLimit, v.MaxLimit)
+	}
+	if s.Inflight*2 < int(limit) {
+		return limit
+	}
+	queue := limit * (1 - float64(v.minRTT)/float64(s.RTT))
+	alpha, beta := 3*step, 6*step
+	switch {
+	case queue <= step:
+		limit += beta // 几乎没有排队，快速增长
+	case queue < alpha:
+		limit += step
+	case queue > beta:
+		limit -= step
+	}
+	return clampLimit(limit, v.MinLimit, v.MaxLimit)
+}
+
+// Gradient 比较短期耗时和长期平均耗时的比值（梯度）调整上限
+// 短期耗时明显高于长期平均时说明开始排队，按比例缩小；否则在 limit*gradient 的基础上额外允许 sqrt(limit) 的排队
+// 字段为 0 时使用默认值，零值可以直接使用，MaxLimit 为 0 表示不限制
+type Gradient struct {
+	MinLimit  int
+	MaxLimit  int
+	Tolerance float64 // 允许短期耗时超过长期平均的倍数，默认 1.5
+	Smoothing float64 // 新上限的权重，默认 0.2
+	Window    int     // 长期平均的样本窗口，默认 600
+	longRTT   float64 // 长期耗时的指数移动平均
+}
+
+func NewGradient(minLimit, maxLimit int) *Gradient {
+	return &Gradient{MinLimit: minLimit, MaxLimit: maxLimit, Tolerance: 1.5, Smoothing: 0.2, Window: 600}
+}
+
+func (g *Gradient) Update(limit float64, s Sample) float64 {
+	if s.RTT <= 0 {
+		return limit
+	}
+	tolerance, smoothing, window := g.Tolerance, g.Smoothing, g.Window
+	if tolerance <= 0 {
+		tolerance = 1.5
+	}
+	if smoothing <= 0 {
+		smoothing = 0.2
+	}
+	if window <= 0 {
+		window = 600
+	}
+	rtt := float64(s.RTT)
+	if g.longRTT == 0 {
+		g.longRTT = rtt
+	} else {
+		factor := 2 / float64(window+1)
+		g.longRTT = g.longRTT*(1-factor) + rtt*factor
+	}
+	if s.Dropped {
+		return clampLimit(limit*0.9, g.MinLimit, g.MaxLimit)
+	}
+	if s.Inflight*2 < int(limit) {
+		return limit
+	}
+	gradient := math.Max(0.5, math.Min(1, tolerance*g.longRTT/rtt))
+	newLimit := limit*gradient + math.Sqrt(limit)
+	limit = limit*(1-smoothing) + newLimit*smoothing
+	return clampLimit(limit, g.MinLimit, g.MaxLimit)
+}
+
+func clampLimit(limit float64, minLimit, maxLimit int) float64 {
+	if maxLimit > 0 && limit > float64(maxLimit) {
+		limit = float64(maxLimit)
+	}
+	return math.Max(limit, float64(max(minLimit, 1)))
+}
+
+// Token 通过 Acquire 获得的并发名额，请求结束后必须调用 Release 或 Ignore 之一
+type Token interface {
+	// Release 归还名额并把结果反馈给算法，latency <= 0 时使用从获得名额开始的耗时
+	Release(success bool, latency time.Duration)
+	// Ignore 归还名额但不反馈给算法，用于调用方主动取消等与服务端负载无关的情况
+	Ignore()
+}
+
+// AdaptiveLimiter 自适应并发限流器，根据请求的耗时和失败情况动态调整允许的最大并发数
+type AdaptiveLimiter struct {
+	mu       sync.Mutex
+	algo     LimitAlgorithm
+	limit    float64
+	inflight int
+	waiters  *list.List // 等待名额的请求，元素为 chan struct{}
+	onChange func(oldLimit, newLimit int)
+	clock    clock.Clock
+}
+
+type adaptiveConfig struct {
+	initialLimit int
+	clock        clock.Clock
+}
+
+type AdaptiveOption func(*adaptiveConfig)
+
+// WithInitialLimit 设置初始并发上限，默认 20
+func WithInitialLimit(limit int) AdaptiveOption {
+	return func(c *adaptiveConfig) {
+		c.initialLimit = limit
+	}
+}
+
+// WithAdaptiveClock 指定计算请求耗时使用的时间来源
+func WithAdaptiveClock(c clock.Clock) AdaptiveOption {
+	return func(cfg *adaptiveConfig) {
+		cfg.clock = c
+	}
+}
+
+func NewAdaptiveLimiter(algo LimitAlgorithm, opts ...AdaptiveOption) *AdaptiveLimiter {
+	cfg := &adaptiveConfig{initialLimit: 20, clock: clock.New()}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return &AdaptiveLimiter{
+		algo:    algo,
+		limit:   float64(max(cfg.initialLimit, 1)),
+		waiters: list.New(),
+		clock:   cfg.clock,
+	}
+}
+
+// OnLimitChange 设置并发上限变化的回调，可用于上报监控，回调在持有锁时执行，不能再调用限流器的方法
+func (l *AdaptiveLimiter) OnLimitChange(fn func(oldLimit, newLimit int)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onChange = fn
+}
+
+// TryAcquire 不等待地获取一个名额，并发数已达上限时返回 ErrLimitExceeded
+func (l *AdaptiveLimiter) TryAcquire() (Token, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inflight >= int(l.limit) {
+		return nil, ErrLimitExceeded
+	}
+	l.inflight++
+	return l.newToken(), nil
+}
+
+// Acquire 获取一个名额，并发数已达上限时按先来先到排队等待，直到有名额或 ctx 结束
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) (Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	if l.inflight < int(l.limit) && l.waiters.Len() == 0 {
+		l.inflight++
+		token := l.newToken()
+		l.mu.Unlock()
+		return token, nil
+	}
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.newToken(), nil // 唤醒方已经为我们预留了名额
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-ready:
+			// 取消的同时被唤醒，把预留的名额让给下一个
+			l.inflight--
+			l.notifyWaiters()
+		default:
+			l.waiters.Remove(elem)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Limit 返回当前的并发上限
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Inflight 返回当前正在处理的请求数
+func (l *AdaptiveLimiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// Waiting 返回正在排队等待名额的请求数
+func (l *AdaptiveLimiter) Waiting() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waiters.Len()
+}
+
+// newToken 为已计入 inflight 的名额创建令牌，调用方需持有锁
+func (l *AdaptiveLimiter) newToken() *adaptiveToken {
+	return &adaptiveToken{limiter: l, start: l.clock.Now(), inflight: l.inflight}
+}
+
+// release 归还名额，sample 不为 nil 时更新并发上限
+func (l *AdaptiveLimiter) release(sample *Sample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	if sample != nil {
+		oldLimit := int(l.limit)
+		l.limit = l.algo.Update(l.limit, *sample)
+		if newLimit := int(l.limit); newLimit != oldLimit && l.onChange != nil {
+			l.onChange(oldLimit, newLimit)
+		}
+	}
+	l.notifyWaiters()
+}
+
+// notifyWaiters 在有空闲名额时按顺序唤醒等待者并为其预留名额，调用方需持有锁
+func (l *AdaptiveLimiter) notifyWaiters() {
+	for l.inflight < int(l.limit) && l.waiters.Len() > 0 {
+		ready := l.waiters.Remove(l.waiters.Front()).(chan struct{})
+		l.inflight++ // 为被唤醒的等待者预留名额
+		close(ready)
+	}
+}
+
+type adaptiveToken struct {
+	limiter  *AdaptiveLimiter
+	start    time.Time
+	inflight int
+	once     sync.Once
+}
+
+func (t *adaptiveToken) Release(success bool, latency time.Duration) {
+	t.once.Do(func() {
+		if latency <= 0 {
+			latency = t.limiter.clock.Since(t.start)
+		}
+		t.limiter.release(&Sample{RTT: latency, Inflight: t.inflight, Dropped: !success})
+	})
+}
+
+func (t *adaptiveToken) Ignore() {
+	t.once.Do(func() {
+		t.limiter.release(nil)
+	})
+}
@@ -0,0 +1,155 @@
+package current_limiting
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// 以固定并发数反复执行请求，返回最终的并发上限
+func feed(algo LimitAlgorithm, limit float64, rounds int, sample func(limit float64) Sample) float64 {
+	for i := 0; i < rounds; i++ {
+		limit = algo.Update(limit, sample(limit))
+	}
+	return limit
+}
+
+func TestAIMD(t *testing.T) {
+	algo := NewAIMD(1, 50)
+	busy := func(limit float64) Sample { return Sample{RTT: 10 * time.Millisecond, Inflight: int(limit)} }
+
+	if got := feed(algo, 10, 5, busy); got != 15 {
+		t.Errorf("没有过载时每次加1，期望15，实际 %v", got)
+	}
+	if got := feed(algo, 10, 100, busy); got != 50 {
+		t.Errorf("不应超过最大上限，实际 %v", got)
+	}
+	if got := algo.Update(10, Sample{Dropped: true, Inflight: 10}); got != 9 {
+		t.Errorf("失败时按0.9缩小，期望9，实际 %v", got)
+	}
+	if got := algo.Update(10, Sample{RTT: time.Millisecond, Inflight: 1}); got != 10 {
+		t.Errorf("并发数远低于上限时不应增长，实际 %v", got)
+	}
+	algo.Timeout = time.Second
+	if got := algo.Update(10, Sample{RTT: 2 * time.Second, Inflight: 10}); got != 9 {
+		t.Errorf("超时视为过载，期望9，实际 %v", got)
+	}
+}
+
+func TestVegas(t *testing.T) {
+	algo := NewVegas(1, 200)
+	// 耗时稳定说明没有排队，上限增长
+	stable := func(limit float64) Sample { return Sample{RTT: 10 * time.Millisecond, Inflight: int(limit)} }
+	limit := feed(algo, 10, 10, stable)
+	if limit <= 10 {
+		t.Fatalf("耗时稳定时上限应增长，实际 %v", limit)
+	}
+	// 耗时翻倍说明大量排队，上限下降
+	congested := func(limit float64) Sample { return Sample{RTT: 20 * time.Millisecond, Inflight: int(limit)} }
+	if got := feed(algo, limit, 10, congested); got >= limit {
+		t.Errorf("排队时上限应下降，之前 %v，之后 %v", limit, got)
+	}
+	if got := algo.Update(100, Sample{RTT: 10 * time.Millisecond, Inflight: 100, Dropped: true}); got != 98 {
+		t.Errorf("失败时减少 log10(limit)，期望98，实际 %v", got)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	algo := NewGradient(1, 1000)
+	stable := func(limit float64) Sample { return Sample{RTT: 10 * time.Millisecond, Inflight: int(limit)} }
+	limit := feed(algo, 20, 20, stable)
+	if limit <= 20 {
+		t.Fatalf("耗时稳定时上限应增长，实际 %v", limit)
+	}
+	// 短期耗时远高于长期平均，梯度降到0.5
+	spike := func(limit float64) Sample { return Sample{RTT: 100 * time.Millisecond, Inflight: int(limit)} }
+	if got := feed(algo, limit, 10, spike); got >= limit {
+		t.Errorf("耗时突增时上限应下降，之前 %v，之后 %v", limit, got)
+	}
+	if got := algo.Update(20, Sample{RTT: time.Millisecond, Inflight: 2}); got != 20 {
+		t.Errorf("并发数远低于上限时不应调整，实际 %v", got)
+	}
+}
+
+// 零值使用默认参数，与构造函数的行为一致
+func TestZeroValueAlgorithms(t *testing.T) {
+	if got := (&AIMD{}).Update(10, Sample{Dropped: true, Inflight: 10}); got != 9 {
+		t.Errorf("AIMD 零值失败时按0.9缩小，期望9，实际 %v", got)
+	}
+
+	stable := func(limit float64) Sample { return Sample{RTT: 10 * time.Millisecond, Inflight: int(limit)} }
+	spike := func(limit float64) Sample { return Sample{RTT: 100 * time.Millisecond, Inflight: int(limit)} }
+	zero, constructed := &Gradient{}, NewGradient(0, 0)
+	for _, sample := range []func(float64) Sample{stable, spike} {
+		got, want := feed(zero, 20, 20, sample), feed(constructed, 20, 20, sample)
+		if got != want || math.IsNaN(got) || got == 20 {
+			t.Errorf("Gradient 零值期望与默认参数一致，期望 %v，实际 %v", want, got)
+		}
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	l := NewAdaptiveLimiter(NewAIMD(1, 10), WithInitialLimit(2))
+	var changes [][2]int
+	l.OnLimitChange(func(oldLimit, newLimit int) {
+		changes = append(changes, [2]int{oldLimit, newLimit})
+	})
+
+	t1, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, _ := l.TryAcquire()
+	if _, err := l.TryAcquire(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("达到上限时期望 ErrLimitExceeded，实际 %v", err)
+	}
+
+	// 排队等待的请求在名额归还后被唤醒
+	acquired := make(chan Token, 1)
+	go func() {
+		token, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- token
+	}()
+	for l.Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	t1.Release(true, 10*time.Millisecond) // 上限 2 -> 3
+	t3 := <-acquired
+	if l.Limit() != 3 || l.Inflight() != 2 {
+		t.Errorf("期望上限3、并发2，实际 %d、%d", l.Limit(), l.Inflight())
+	}
+
+	t2.Release(false, 0) // 失败，上限 3 -> 2.7
+	t2.Release(false, 0) // 重复归还无效
+	t3.Ignore()
+	if l.Limit() != 2 || l.Inflight() != 0 {
+		t.Errorf("期望上限2、并发0，实际 %d、%d", l.Limit(), l.Inflight())
+	}
+	if len(changes) != 2 || changes[0] != [2]int{2, 3} || changes[1] != [2]int{3, 2} {
+		t.Errorf("上限变化回调错误: %v", changes)
+	}
+}
+
+func TestAdaptiveLimiterCancel(t *testing.T) {
+	l := NewAdaptiveLimiter(NewAIMD(1, 10), WithInitialLimit(1))
+	token, _ := l.TryAcquire()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望 context.DeadlineExceeded，实际 %v", err)
+	}
+	if l.Waiting() != 0 {
+		t.Errorf("取消后不应继续排队，实际 %d", l.Waiting())
+	}
+
+	token.Ignore()
+	if _, err := l.TryAcquire(); err != nil {
+		t.Errorf("归还后应能获取名额: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package current_limiting
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"GoTools/clock"
+)
+
+// FixedWindow 固定窗口计数器，每个窗口最多允许 limit 个请求，窗口按 window 对齐
+// 实现简单，但窗口交界处最多可能放过 2*limit 个请求
+type FixedWindow struct {
+	mu     sync.Mutex
+	limit  int64
+	window time.Duration
+	start  time.Time // 当前窗口的开始时间
+	count  int64     // 当前窗口已允许的请求数
+	clock  clock.Clock
+}
+
+func NewFixedWindow(limit int64, window time.Duration, opts ...Option) *FixedWindow {
+	o := newOptions(opts)
+	return &FixedWindow{
+		limit:  limit,
+		window: window,
+		start:  o.clock.Now().Truncate(window),
+		clock:  o.clock,
+	}
+}
+
+func (w *FixedWindow) Allow() bool {
+	return w.AllowN(w.clock.Now(), 1)
+}
+
+// AllowN 判断当前窗口是否还能容纳 n 个请求，能则计入，n <= 0 时返回 false
+func (w *FixedWindow) AllowN(now time.Time, n int64) bool {
+	if n <= 0 {
+		return false // 负数会把配额加回窗口
+	}
+	_, ok := w.tryN(now, n)
+	return ok
+}
+
+func (w *FixedWindow) Wait(ctx context.Context) error {
+	return w.WaitN(ctx, 1)
+}
+
+// WaitN 等待直到某个窗口能容纳 n 个请求，n 超过 limit 时返回 ErrExceedsCapacity
+func (w *FixedWindow) WaitN(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	if n > w.limit {
+		return ErrExceedsCapacity
+	}
+	return waitUntilAllowed(ctx, w.clock, func(now time.Time) (time.Duration, bool) {
+		return w.tryN(now, n)
+	})
+}
+
+// Remaining 返回当前窗口剩余的请求数
+func (w *FixedWindow) Remaining() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.advance(w.clock.Now())
+	return max(w.limit-w.count, 0)
+}
+
+// Reset 返回当前窗口结束的时间
+func (w *FixedWindow) Reset() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.advance(w.clock.Now())
+	return w.start.Add(w.window)
+}
+
+// Quota 返回当前窗口的配额状态，配额在窗口结束时恢复
+func (w *FixedWindow) Quota() Quota {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+	w.advance(now)
+	q := Quota{Limit: w.limit, Remaining: max(w.limit-w.count, 0)}
+	untilEnd := w.start.Add(w.window).Sub(now)
+	if w.count > 0 {
+		q.Reset = untilEnd
+	}
+	if q.Remaining == 0 {
+		q.RetryAfter = untilEnd
+	}
+	return q
+}
+
+// tryN 尝试计入 n 个请求，失败时返回距离下一个窗口的时间
+func (w *FixedWindow) tryN(now time.Time, n int64) (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.advance(now)
+	if w.count+n <= w.limit {
+		w.count += n
+		return 0, true
+	}
+	if n > w.limit {
+		return infDuration, false
+	}
+	return w.start.Add(w.window).Sub(now), false
+}
+
+// advance 进入 now 所在的窗口，调用方需持有锁
+func (w *FixedWindow) advance(now time.Time) {
+	if now.Sub(w.start) >= w.window {
+		w.start = now.Truncate(w.window)
+		w.count = 0
+	}
+}
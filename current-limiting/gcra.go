@@ -0,0 +1,121 @@
+package current_limiting
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"GoTools/clock"
+)
+
+// GCRA 通用信元速率算法，只保存一个理论到达时间（TAT），效果等价于令牌桶但状态更小
+// 请求以 1/rate 的间隔均匀到达时全部放行，最多允许 burst 个请求的突发
+type GCRA struct {
+	mu       sync.Mutex
+	interval time.Duration // 发射间隔 1/rate
+	burst    int64
+	tat      time.Time // 理论到达时间
+	clock    clock.Clock
+}
+
+// NewGCRA 创建每秒允许 rate 个请求、最多突发 burst 个请求的限流器，创建后即可突发，burst 小于 1 时按 1 处理，rate 必须大于 0
+func NewGCRA(rate int64, burst int64, opts ...Option) *GCRA {
+	o := newOptions(opts)
+	return &GCRA{
+		interval: emissionInterval(rate),
+		burst:    max(burst, 1),
+		tat:      o.clock.Now(),
+		clock:    o.clock,
+	}
+}
+
+func (g *GCRA) Allow() bool {
+	return g.AllowN(g.clock.Now(), 1)
+}
+
+// AllowN 判断 now 时刻是否允许 n 个请求，允许则推进理论到达时间，n <= 0 时返回 false
+func (g *GCRA) AllowN(now time.Time, n int64) bool {
+	if n <= 0 {
+		return false // 负数会让理论到达时间倒退
+	}
+	_, ok := g.tryN(now, n)
+	return ok
+}
+
+func (g *GCRA) Wait(ctx context.Context) error {
+	return g.WaitN(ctx, 1)
+}
+
+// WaitN 等待直到允许 n 个请求，n 超过 burst 时返回 ErrExceedsCapacity
+func (g *GCRA) WaitN(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	if n > g.burst {
+		return ErrExceedsCapacity
+	}
+	return waitUntilAllowed(ctx, g.clock, func(now time.Time) (time.Duration, bool) {
+		return g.tryN(now, n)
+	})
+}
+
+// Remaining 返回当前还能立即放行的请求数
+func (g *GCRA) Remaining() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.clock.Now()
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	used := int64((tat.Sub(now) + g.interval - 1) / g.interval)
+	return max(g.burst-used, 0)
+}
+
+// Quota 返回配额状态，理论到达时间回到当前时间时配额完全恢复
+func (g *GCRA) Quota() Quota {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.clock.Now()
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	used := int64((tat.Sub(now) + g.interval - 1) / g.interval)
+	allowAt := tat.Add(g.interval - time.Duration(g.burst)*g.interval)
+	return Quota{
+		Limit:      g.burst,
+		Remaining:  max(g.burst-used, 0),
+		Reset:      tat.Sub(now),
+		RetryAfter: max(allowAt.Sub(now), 0),
+	}
+}
+
+// emissionInterval 返回每秒 rate 个请求时相邻两个请求的间隔，rate 超过 1e9 时按每纳秒一个处理
+func emissionInterval(rate int64) time.Duration {
+	if rate <= 0 {
+		panic("current_limiting: rate must be greater than 0")
+	}
+	return max(time.Second/time.Duration(rate), time.Nanosecond)
+}
+
+// tryN 尝试放行 n 个请求，失败时返回还需等待的时间
+func (g *GCRA) tryN(now time.Time, n int64) (time.Duration, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n > g.burst {
+		return infDuration, false
+	}
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(time.Duration(n) * g.interval)
+	// 允许 TAT 领先当前时间最多 burst 个间隔
+	allowAt := newTat.Add(-time.Duration(g.burst) * g.interval)
+	if now.Before(allowAt) {
+		return allowAt.Sub(now), false
+	}
+	g.tat = newTat
+	return 0, true
+}
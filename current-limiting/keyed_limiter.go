@@ -0,0 +1,246 @@
+package current_limiting
+
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"GoTools/algorithm"
+	"GoTools/clock"
+)
+
+// KeyLimit 单个键的令牌桶配置
+type KeyLimit struct {
+	Rate     int64 `json:"rate" yaml:"rate"`         // 每秒生成的令牌数
+	Capacity int64 `json:"capacity" yaml:"capacity"` // 令牌桶容量
+}
+
+type keyedEntry struct {
+	limiter *Limiter
+	limit   KeyLimit // 当前生效的配置，与最新配置不同时在下次访问时更新
+}
+
+// keyedLimits 默认配置和单独配置，修改时整体替换，读取时不加锁
+type keyedLimits struct {
+	defaults  KeyLimit
+	overrides map[string]KeyLimit
+}
+
+// keyedShard 一个分片，独立加锁，不同分片的键互不影响
+type keyedShard struct {
+	mu       sync.Mutex
+	limiters *algorithm.TimeoutCache[string, *keyedEntry]
+}
+
+// KeyedLimiter 按键（用户 ID、IP、API Key 等）限流，每个键在第一次访问时创建自己的令牌桶
+// 键按哈希分散到多个独立加锁的分片上，避免所有请求竞争同一把锁，键的数量上限平均分配到每个分片，
+// 分片内键的数量超过上限时淘汰最久未访问的键，空闲超过 idleTTL 的键也会被清理，被清理的键再次访问时重新创建
+type KeyedLimiter struct {
+	mu      sync.Mutex // 串行化配置的修改
+	limits  atomic.Pointer[keyedLimits]
+	shards  []*keyedShard
+	seed    maphash.Seed
+	idleTTL time.Duration
+	clock   clock.Clock
+}
+
+type keyedConfig struct {
+	maxKeys int
+	shards  int
+	idleTTL time.Duration
+	clock   clock.Clock
+}
+
+type KeyedOption func(*keyedConfig)
+
+// WithMaxKeys 设置最多保留的键数量，默认 10000
+func WithMaxKeys(n int) KeyedOption {
+	return func(c *keyedConfig) {
+		c.maxKeys = n
+	}
+}
+
+// WithKeyedShards 设置分片数量，默认 32，maxKeys 较小时会减少分片，保证每个分片至少容纳 256 个键
+func WithKeyedShards(n int) KeyedOption {
+	return func(c *keyedConfig) {
+		c.shards = n
+	}
+}
+
+// WithIdleTTL 设置键的空闲时间，超过后清理，<= 0 表示只按数量淘汰，默认 10 分钟
+func WithIdleTTL(ttl time.Duration) KeyedOption {
+	return func(c *keyedConfig) {
+		c.idleTTL = ttl
+	}
+}
+
+// WithKeyedClock 指定时间来源，同时用于空闲判断和每个键的令牌桶
+func WithKeyedClock(c clock.Clock) KeyedOption {
+	return func(cfg *keyedConfig) {
+		cfg.clock = c
+	}
+}
+
+// NewKeyedLimiter 创建按键限流器，未单独配置的键每秒生成 rate 个令牌，容量为 capacity
+func NewKeyedLimiter(rate, capacity int64, opts ...KeyedOption) *KeyedLimiter {
+	cfg := &keyedConfig{maxKeys: 10000, shards: 32, idleTTL: 10 * time.Minute, clock: clock.New()}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	shardNum := min(max(cfg.maxKeys/256, 1), max(cfg.shards, 1))
+	perShard := (cfg.maxKeys + shardNum - 1) / shardNum
+	k := &KeyedLimiter{
+		shards:  make([]*keyedShard, shardNum),
+		seed:    maphash.MakeSeed(),
+		idleTTL: cfg.idleTTL,
+		clock:   cfg.clock,
+	}
+	for i := range k.shards {
+		k.shards[i] = &keyedShard{limiters: algorithm.NewTimeoutCache[string, *keyedEntry](perShard,
+			algorithm.WithEvictPolicy(algorithm.EvictLRU), algorithm.WithClock(cfg.clock))}
+	}
+	k.limits.Store(&keyedLimits{
+		defaults:  KeyLimit{Rate: rate, Capacity: capacity},
+		overrides: make(map[string]KeyLimit),
+	})
+	return k
+}
+
+func (k *KeyedLimiter) shard(key string) *keyedShard {
+	return k.shards[maphash.String(k.seed, key)%uint64(len(k.shards))]
+}
+
+// Get 返回键对应的令牌桶，不存在时创建，新建的令牌桶是满的，只锁住键所在的分片
+func (k *KeyedLimiter) Get(key string) *Limiter {
+	want := k.limits.Load().limitFor(key)
+	s := k.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.limiters.Get(key)
+	if !ok {
+		entry = &keyedEntry{
+			limiter: NewLimiter(want.Rate, want.Capacity, WithClock(k.clock), WithInitialTokens(want.Capacity)),
+			limit:   want,
+		}
+	} else if entry.limit != want {
+		// 配置已更新，保留已有的令牌数
+		entry.limiter.SetRate(want.Rate)
+		entry.limiter.SetCapacity(want.Capacity)
+		entry.limit = want
+	}
+	s.limiters.SetWithTTL(key, entry, k.idleTTL) // 每次访问都刷新空闲时间
+	return entry.limiter
+}
+
+func (k *KeyedLimiter) Allow(key string) bool {
+	return k.Get(key).Allow()
+}
+
+func (k *KeyedLimiter) AllowN(key string, now time.Time, n int64) bool {
+	return k.Get(key).AllowN(now, n)
+}
+
+func (k *KeyedLimiter) Wait(ctx context.Context, key string) error {
+	return k.Get(key).Wait(ctx)
+}
+
+func (k *KeyedLimiter) WaitN(ctx context.Context, key string, n int64) error {
+	return k.Get(key).WaitN(ctx, n)
+}
+
+// SetOverride 为单个键设置单独的速率和容量，已存在的令牌桶在下次访问时生效
+func (k *KeyedLimiter) SetOverride(key string, rate, capacity int64) {
+	k.updateLimits(func(l *keyedLimits) {
+		l.overrides[key] = KeyLimit{Rate: rate, Capacity: capacity}
+	})
+}
+
+// RemoveOverride 删除键的单独配置，恢复使用默认配置
+func (k *KeyedLimiter) RemoveOverride(key string) {
+	k.updateLimits(func(l *keyedLimits) {
+		delete(l.overrides, key)
+	})
+}
+
+// SetRate 批量修改默认速率，overrides 中的键会同时更新单独配置
+// 未出现在 overrides 中的单独配置保持不变
+func (k *KeyedLimiter) SetRate(rate int64, overrides map[string]int64) {
+	k.updateLimits(func(l *keyedLimits) {
+		l.defaults.Rate = rate
+		for key, r := range overrides {
+			limit, ok := l.overrides[key]
+			if !ok {
+				limit.Capacity = l.defaults.Capacity
+			}
+			limit.Rate = r
+			l.overrides[key] = limit
+		}
+	})
+}
+
+// Reload 用新的默认配置和单独配置整体替换旧配置，通常在配置文件重新加载后调用
+// 已存在的令牌桶保留当前令牌数，在下次访问时按新配置调整
+func (k *KeyedLimiter) Reload(defaults KeyLimit, overrides map[string]KeyLimit) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	next := &keyedLimits{defaults: defaults, overrides: make(map[string]KeyLimit, len(overrides))}
+	for key, limit := range overrides {
+		next.overrides[key] = limit
+	}
+	k.limits.Store(next)
+}
+
+// Limit 返回键当前的配置
+func (k *KeyedLimiter) Limit(key string) KeyLimit {
+	return k.limits.Load().limitFor(key)
+}
+
+// Remove 立即删除键的令牌桶，返回键是否存在
+func (k *KeyedLimiter) Remove(key string) bool {
+	s := k.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limiters.Delete(key)
+}
+
+// Len 返回当前保留的键数量，包含已空闲超时但还未被清理的键
+func (k *KeyedLimiter) Len() int {
+	total := 0
+	for _, s := range k.shards {
+		total += s.limiters.Size()
+	}
+	return total
+}
+
+// Cleanup 清理所有空闲超时的键，返回清理的数量
+func (k *KeyedLimiter) Cleanup() int {
+	total := 0
+	for _, s := range k.shards {
+		total += s.limiters.DeleteExpired()
+	}
+	return total
+}
+
+// updateLimits 复制一份配置修改后整体替换，正在读取旧配置的 Get 不受影响
+func (k *KeyedLimiter) updateLimits(update func(l *keyedLimits)) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	old := k.limits.Load()
+	next := &keyedLimits{defaults: old.defaults, overrides: make(map[string]KeyLimit, len(old.overrides))}
+	for key, limit := range old.overrides {
+		next.overrides[key] = limit
+	}
+	update(next)
+	k.limits.Store(next)
+}
+
+// limitFor 返回键应使用的配置
+func (l *keyedLimits) limitFor(key string) KeyLimit {
+	if limit, ok := l.overrides[key]; ok {
+		return limit
+	}
+	return l.defaults
+}
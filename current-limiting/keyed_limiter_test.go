@@ -0,0 +1,141 @@
+package current_limiting
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"GoTools/clock"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	k := NewKeyedLimiter(1, 3, WithKeyedClock(fc))
+	k.SetOverride("vip", 10, 10)
+
+	// 每个键独立计数，新键的令牌桶是满的
+	if !k.AllowN("alice", fc.Now(), 3) || k.Allow("alice") {
+		t.Error("alice 应恰好允许3个请求")
+	}
+	if !k.Allow("bob") {
+		t.Error("bob 不应受 alice 的影响")
+	}
+	if !k.AllowN("vip", fc.Now(), 10) {
+		t.Error("vip 应使用单独配置的容量")
+	}
+	if k.Len() != 3 {
+		t.Errorf("期望3个键，实际 %d 个", k.Len())
+	}
+
+	fc.Advance(time.Second)
+	if !k.Allow("alice") || k.Allow("alice") {
+		t.Error("1秒后 alice 应恰好恢复1个令牌")
+	}
+
+	k.RemoveOverride("vip")
+	if l := k.Get("vip"); l.Capacity() != 3 || l.Rate() != 1 {
+		t.Errorf("删除单独配置后应恢复默认配置，实际 rate=%d capacity=%d", l.Rate(), l.Capacity())
+	}
+}
+
+func TestKeyedLimiterEviction(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	k := NewKeyedLimiter(1, 1, WithKeyedClock(fc), WithMaxKeys(2), WithIdleTTL(time.Minute))
+
+	k.Allow("a")
+	k.Allow("b")
+	k.Get("a")   // a 最近访问
+	k.Allow("c") // 超过上限，淘汰最久未访问的 b
+	if k.Len() != 2 {
+		t.Errorf("期望2个键，实际 %d 个", k.Len())
+	}
+	if !k.Allow("b") {
+		t.Error("b 被淘汰后重新创建，令牌桶应是满的")
+	}
+
+	// 空闲超时的键被清理
+	fc.Advance(30 * time.Second)
+	k.Get("b")
+	fc.Advance(45 * time.Second)
+	if n := k.Cleanup(); n != 1 {
+		t.Errorf("期望清理1个空闲键，实际 %d 个", n)
+	}
+	if !k.Remove("b") || k.Len() != 0 {
+		t.Errorf("期望删除 b 后为空，实际 %d 个", k.Len())
+	}
+}
+
+func TestKeyedLimiterReload(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	k := NewKeyedLimiter(1, 5, WithKeyedClock(fc))
+	k.SetOverride("vip", 10, 20)
+	k.AllowN("user", fc.Now(), 3)
+	k.Get("vip")
+
+	k.SetRate(2, map[string]int64{"vip": 50, "new": 7})
+	if got := k.Limit("vip"); got != (KeyLimit{Rate: 50, Capacity: 20}) {
+		t.Errorf("vip 配置错误: %+v", got)
+	}
+	if got := k.Limit("new"); got != (KeyLimit{Rate: 7, Capacity: 5}) {
+		t.Errorf("new 应使用默认容量: %+v", got)
+	}
+	if l := k.Get("user"); l.Rate() != 2 || l.Tokens() != 2 {
+		t.Errorf("已有的令牌桶应更新速率并保留令牌，实际 rate=%d tokens=%f", l.Rate(), l.Tokens())
+	}
+
+	k.Reload(KeyLimit{Rate: 3, Capacity: 1}, map[string]KeyLimit{"gold": {Rate: 100, Capacity: 100}})
+	if got := k.Limit("vip"); got != (KeyLimit{Rate: 3, Capacity: 1}) {
+		t.Errorf("Reload 后 vip 应恢复默认配置: %+v", got)
+	}
+	if l := k.Get("user"); l.Capacity() != 1 || l.Tokens() > 1 {
+		t.Errorf("缩容后令牌数不应超过新容量，实际 capacity=%d tokens=%f", l.Capacity(), l.Tokens())
+	}
+	if got := k.Limit("gold"); got.Rate != 100 {
+		t.Errorf("gold 配置错误: %+v", got)
+	}
+}
+
+// 不同分片的键互不阻塞
+func TestKeyedLimiterShards(t *testing.T) {
+	k := NewKeyedLimiter(1, 1)
+	if len(k.shards) != 32 {
+		t.Fatalf("期望32个分片，实际 %d 个", len(k.shards))
+	}
+	if small := NewKeyedLimiter(1, 1, WithMaxKeys(2)); len(small.shards) != 1 {
+		t.Errorf("键数量上限较小时期望1个分片，实际 %d 个", len(small.shards))
+	}
+
+	other := "b"
+	for i := 0; k.shard(other) == k.shard("a"); i++ {
+		other = fmt.Sprintf("b%d", i)
+	}
+	locked := k.shard("a")
+	locked.mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		k.Allow(other)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("其他分片的键不应被阻塞")
+	}
+	locked.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				k.Get(fmt.Sprintf("key-%d", j))
+			}
+		}()
+	}
+	wg.Wait()
+	if k.Len() != 101 {
+		t.Errorf("期望101个键，实际 %d 个", k.Len())
+	}
+}
@@ -0,0 +1,136 @@
+package current_limiting
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"GoTools/clock"
+)
+
+var ErrQueueFull = errors.New("leaky bucket queue is full")
+
+// LeakyBucket 漏桶，请求进入队列后以恒定速率流出，用于把突发流量整形为匀速流量
+// 队列中最多排队 capacity 个请求，与令牌桶不同，它不允许任何突发
+type LeakyBucket struct {
+	mu       sync.Mutex
+	interval time.Duration // 相邻两个请求流出的间隔
+	capacity int64         // 最多排队的请求数
+	next     time.Time     // 下一个请求可以流出的时间
+	clock    clock.Clock
+}
+
+// NewLeakyBucket 创建每秒流出 rate 个请求、最多排队 capacity 个请求的漏桶，rate 必须大于 0
+func NewLeakyBucket(rate int64, capacity int64, opts ...Option) *LeakyBucket {
+	o := newOptions(opts)
+	return &LeakyBucket{
+		interval: emissionInterval(rate),
+		capacity: capacity,
+		next:     o.clock.Now(),
+		clock:    o.clock,
+	}
+}
+
+// Allow 队列为空且当前可以流出时允许请求，不会排队
+func (b *LeakyBucket) Allow() bool {
+	return b.AllowN(b.clock.Now(), 1)
+}
+
+// AllowN 队列为空时允许 n 个请求并占用之后 n 个流出间隔，不会排队，n 不合法或超过队列容量时返回 false
+func (b *LeakyBucket) AllowN(now time.Time, n int64) bool {
+	if n <= 0 || n > b.capacity {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.next.After(now) {
+		return false
+	}
+	b.next = now.Add(time.Duration(n) * b.interval)
+	return true
+}
+
+func (b *LeakyBucket) Wait(ctx context.Context) error {
+	return b.WaitN(ctx, 1)
+}
+
+// WaitN 把 n 个请求放入队列并等待它们全部流出
+// 队列已满时返回 ErrQueueFull，ctx 的截止时间早于流出时间时返回 ErrWouldExceedDeadline
+// 等待期间 ctx 被取消时，若之后没有新的请求排队则归还占用的位置
+func (b *LeakyBucket) WaitN(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	if n > b.capacity {
+		return ErrExceedsCapacity
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	now := b.clock.Now()
+	start := b.next
+	if start.Before(now) {
+		start = now
+	}
+	if b.queued(now)+n > b.capacity {
+		b.mu.Unlock()
+		return ErrQueueFull
+	}
+	end := start.Add(time.Duration(n) * b.interval)
+	delay := end.Add(-b.interval).Sub(now) // 最后一个请求流出的时间
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		b.mu.Unlock()
+		return ErrWouldExceedDeadline
+	}
+	b.next = end
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := b.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		if b.next.Equal(end) {
+			b.next = start // 排在最后，可以直接让出位置
+		}
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Queued 返回当前排队等待流出的请求数
+func (b *LeakyBucket) Queued() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.queued(b.clock.Now())
+}
+
+// Quota 返回漏桶的配额状态，Limit 为队列长度，Remaining 为队列剩余的位置
+func (b *LeakyBucket) Quota() Quota {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	q := Quota{Limit: b.capacity, Remaining: max(b.capacity-b.queued(now), 0)}
+	if b.next.After(now) {
+		q.Reset = b.next.Sub(now)
+		q.RetryAfter = q.Reset // Allow 只在队列为空时放行
+	}
+	return q
+}
+
+// queued 计算 now 时刻排队的请求数，正在流出的请求不计入，调用方需持有锁
+func (b *LeakyBucket) queued(now time.Time) int64 {
+	if !b.next.After(now) {
+		return 0
+	}
+	pending := b.next.Sub(now)
+	return int64((pending+b.interval-1)/b.interval) - 1
+}
@@ -2,36 +2,97 @@ package current_limiting
 
 import (
 	"context"
+	"errors"
+	"math"
 	"sync"
 	"time"
+
+	"GoTools/clock"
+)
+
+var (
+	ErrExceedsCapacity     = errors.New("requested tokens exceed limiter capacity")
+	ErrWouldExceedDeadline = errors.New("wait would exceed context deadline")
 )
 
+// 不会结束的等待时间，用于速率为 0 等永远拿不到令牌的情况
+const infDuration = time.Duration(math.MaxInt64)
+
 type Limiter struct {
-	mu        sync.Mutex // 互斥锁，确保并发安全
-	rate      int64      // 每秒允许的请求数
-	capacity  int64      // 令牌桶的容量
-	tokens    float64    // 当前令牌数
-	lastCheck time.Time  // 上次检查时间
+	mu        sync.Mutex  // 互斥锁，确保并发安全
+	rate      int64       // 每秒允许的请求数
+	capacity  int64       // 令牌桶的容量
+	tokens    float64     // 当前令牌数，存在预约时可能为负
+	lastCheck time.Time   // 上次检查时间
+	lastEvent time.Time   // 最近一次预约生效的时间
+	clock     clock.Clock // 时间来源
+}
+
+type options struct {
+	clock         clock.Clock
+	initialTokens int64
+}
+
+// Option 所有本地限流器共用的选项
+type Option func(*options)
+
+// WithClock 指定时间来源，测试中可传入 clock.FakeClock
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithInitialTokens 设置令牌桶创建时的令牌数，默认为 0，仅对 Limiter 生效
+func WithInitialTokens(tokens int64) Option {
+	return func(o *options) {
+		o.initialTokens = tokens
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.New()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
 }
 
-func NewLimiter(rate int64, capacity int64) *Limiter {
-	return &Limiter{
-		rate:      rate,
-		capacity:  capacity,
-		tokens:    0, // 初始化令牌数为空，避免请求太多击穿后端服务
-		lastCheck: time.Now(),
+func NewLimiter(rate int64, capacity int64, opts ...Option) *Limiter {
+	o := newOptions(opts)
+	l := &Limiter{
+		rate:     rate,
+		capacity: capacity,
+		tokens:   float64(min(max(o.initialTokens, 0), capacity)), // 默认为空，避免请求太多击穿后端服务
+		clock:    o.clock,
 	}
+	l.lastCheck = l.clock.Now()
+	return l
 }
 
 func (l *Limiter) durationFromTokens(tokens float64) time.Duration {
 	if tokens <= 0 {
 		return 0
 	}
-	return time.Duration(float64(time.Second) * (tokens / float64(l.rate)))
+	if l.rate <= 0 {
+		return infDuration
+	}
+	seconds := tokens / float64(l.rate)
+	if seconds >= infDuration.Seconds() {
+		return infDuration
+	}
+	return time.Duration(float64(time.Second) * seconds)
 }
 
 func (l *Limiter) updateTokens() {
-	now := time.Now()
+	l.advance(l.clock.Now())
+}
+
+// advance 把令牌数补充到 now 时刻，now 早于上次检查时间时不补充
+func (l *Limiter) advance(now time.Time) {
+	if now.Before(l.lastCheck) {
+		return
+	}
 	elapsed := now.Sub(l.lastCheck).Seconds() // 计算自上次检查以来经过的秒数
 	l.lastCheck = now
 
@@ -62,30 +123,17 @@ func (l *Limiter) SetCapacity(capacity int64) {
 }
 
 func (l *Limiter) Allow() bool {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	return l.AllowN(l.clock.Now(), 1)
+}
 
-	l.updateTokens() // 更新令牌数
-	if l.tokens >= 1 {
-		l.tokens -= 1 // 消耗一个令牌
-		return true
-	}
-	return false // 没有足够的令牌
+// AllowN 判断 now 时刻是否有 n 个令牌可用，有则立即消耗
+func (l *Limiter) AllowN(now time.Time, n int64) bool {
+	return l.reserveN(now, n, 0).ok
 }
 
-func (l *Limiter) Wait() {
-	for {
-		l.mu.Lock()
-		l.updateTokens()
-		if l.tokens >= 1 {
-			l.tokens -= 1
-			l.mu.Unlock()
-			return
-		}
-		waitTime := l.durationFromTokens(1 - l.tokens)
-		l.mu.Unlock()
-		time.Sleep(waitTime)
-	}
+// Wait 阻塞直到拿到一个令牌或 ctx 结束
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
 }
 
 // 归还令牌
@@ -104,40 +152,178 @@ func (l *Limiter) Return(tokens float64) {
 }
 
 // 等待N个令牌
+// n 超过容量时直接返回 ErrExceedsCapacity，ctx 的截止时间早于令牌可用时间时返回 ErrWouldExceedDeadline
+// 等待期间 ctx 被取消会归还尚未被后续请求占用的令牌
 func (l *Limiter) WaitN(ctx context.Context, n int64) error {
 	if n <= 0 {
 		return nil // 不需要等待
 	}
+	if n > l.Capacity() {
+		return ErrExceedsCapacity
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := l.clock.Now()
+	maxWait := infDuration
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = time.Until(deadline) // ctx 的截止时间总是基于真实时间
+	}
+	r := l.reserveN(now, n, maxWait)
+	if !r.ok {
+		return ErrWouldExceedDeadline
+	}
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+
+	// 等待指定时间或上下文取消
+	timer := l.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil // 成功获取所有令牌
+	case <-ctx.Done():
+		r.Cancel() // 上下文取消，归还令牌
+		return ctx.Err()
+	}
+}
 
+// Capacity 返回令牌桶容量
+func (l *Limiter) Capacity() int64 {
 	l.mu.Lock()
-	l.updateTokens() // 更新令牌数
+	defer l.mu.Unlock()
+	return l.capacity
+}
 
-	// 检查是否有足够的令牌
-	if l.tokens >= float64(n) {
-		l.tokens -= float64(n)
-		l.mu.Unlock()
-		return nil
+// Rate 返回每秒生成的令牌数
+func (l *Limiter) Rate() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Tokens 返回当前可用的令牌数，存在未生效的预约时可能为负
+func (l *Limiter) Tokens() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updateTokens()
+	return l.tokens
+}
+
+// Quota 返回令牌桶的配额状态
+func (l *Limiter) Quota() Quota {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updateTokens()
+	return Quota{
+		Limit:      l.capacity,
+		Remaining:  max(int64(math.Floor(l.tokens)), 0),
+		Reset:      l.durationFromTokens(float64(l.capacity) - l.tokens),
+		RetryAfter: l.durationFromTokens(1 - l.tokens),
 	}
+}
+
+// Reservation 预约的令牌，在 Delay 之后才能使用，不再需要时调用 Cancel 归还
+type Reservation struct {
+	ok        bool
+	lim       *Limiter
+	tokens    float64   // 预约的令牌数
+	timeToAct time.Time // 令牌可用的时间
+}
 
-	// 计算需要等待的时间
-	needTokens := float64(n) - l.tokens
-	waitDuration := l.durationFromTokens(needTokens)
-	l.tokens -= float64(n)   // 先扣除令牌，防止其他请求进来时重复计算
-	l.lastCheck = time.Now() // 记录当前时间，避免重复计算
-	l.mu.Unlock()
+// ReserveN 预约 n 个令牌，返回的预约在 OK 为 false 时不可用（n 超过容量或永远拿不到令牌）
+func (l *Limiter) ReserveN(n int64) *Reservation {
+	return l.reserveN(l.clock.Now(), n, infDuration)
+}
 
-	// 等待指定时间或上下文取消
-	for {
-		select {
-		case <-time.After(waitDuration): // 不考虑过分严苛的限流
-			return nil // 成功获取所有令牌
-		case <-ctx.Done():
-			// 上下文取消，归还令牌
-			l.mu.Lock()
-			l.tokens += float64(n)
-			l.updateTokens() // 更新令牌数
-			l.mu.Unlock()
-			return ctx.Err()
+// reserveWithin 预约 n 个令牌，需要等待超过 maxWait 时预约失败且不消耗令牌
+func (l *Limiter) reserveWithin(n int64, maxWait time.Duration) *Reservation {
+	return l.reserveN(l.clock.Now(), n, maxWait)
+}
+
+// OK 返回预约是否成功
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay 返回还需等待多久才能使用预约的令牌
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return infDuration
+	}
+	return r.DelayFrom(r.lim.clock.Now())
+}
+
+// DelayFrom 返回从 now 开始还需等待的时间，预约失败时返回一个无限长的时间
+func (r *Reservation) DelayFrom(now time.Time) time.Duration {
+	if !r.ok {
+		return infDuration
+	}
+	if delay := r.timeToAct.Sub(now); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// Cancel 取消预约，归还还没有被后续预约占用的令牌，预约生效之后调用不会有任何效果
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+	l := r.lim
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	if r.tokens == 0 || !r.timeToAct.After(now) {
+		return // 预约已经生效，令牌视为已使用
+	}
+	// 后续预约是基于本次扣减后的令牌数计算的，它们占用的部分不能归还
+	restore := r.tokens - float64(l.rate)*l.lastEvent.Sub(r.timeToAct).Seconds()
+	r.tokens = 0
+	if restore <= 0 {
+		return
+	}
+	l.advance(now)
+	l.tokens += restore
+	if l.tokens > float64(l.capacity) {
+		l.tokens = float64(l.capacity)
+	}
+	if r.timeToAct.Equal(l.lastEvent) {
+		// 本次是最后一个预约，回退最近事件时间
+		prevEvent := r.timeToAct.Add(-l.durationFromTokens(restore))
+		if !prevEvent.Before(now) {
+			l.lastEvent = prevEvent
 		}
 	}
 }
+
+// reserveN 在 now 时刻预约 n 个令牌，需要等待的时间超过 maxWait 时预约失败且不消耗令牌
+func (l *Limiter) reserveN(now time.Time, n int64, maxWait time.Duration) *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r := &Reservation{lim: l, tokens: float64(n)}
+	if n <= 0 || n > l.capacity {
+		return r // n 不合法或容量不足，永远无法满足
+	}
+	l.advance(now)
+	tokens := l.tokens - float64(n)
+	var wait time.Duration
+	if tokens < 0 {
+		wait = l.durationFromTokens(-tokens)
+	}
+	if wait > maxWait || wait == infDuration {
+		return r
+	}
+	l.tokens = tokens
+	r.ok = true
+	r.timeToAct = now.Add(wait)
+	if r.timeToAct.After(l.lastEvent) {
+		l.lastEvent = r.timeToAct
+	}
+	return r
+}
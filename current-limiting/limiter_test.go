@@ -2,8 +2,11 @@ package current_limiting
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"GoTools/clock"
 )
 
 // 测试限流器的创建
@@ -28,7 +31,8 @@ func TestNewLimiter(t *testing.T) {
 // 测试Allow方法
 func TestAllow(t *testing.T) {
 	// 每秒10个令牌，容量10
-	limiter := NewLimiter(10, 10)
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewLimiter(10, 10, WithClock(fc))
 
 	// 初始令牌为0，应该不允许
 	if limiter.Allow() {
@@ -36,7 +40,7 @@ func TestAllow(t *testing.T) {
 	}
 
 	// 等待足够长时间让令牌充满
-	time.Sleep(time.Second * 2)
+	fc.Advance(time.Second * 2)
 
 	// 应该允许10个请求
 	count := 0
@@ -51,7 +55,7 @@ func TestAllow(t *testing.T) {
 	}
 
 	// 再等待0.5秒，应该补充5个令牌
-	time.Sleep(time.Millisecond * 500)
+	fc.Advance(time.Millisecond * 500)
 	count = 0
 	for i := 0; i < 10; i++ {
 		if limiter.Allow() {
@@ -67,27 +71,37 @@ func TestAllow(t *testing.T) {
 // 测试Wait方法
 func TestWait(t *testing.T) {
 	// 每秒1个令牌，容量1
-	limiter := NewLimiter(1, 1)
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewLimiter(1, 1, WithClock(fc))
 
 	// 先获取一个令牌，令牌桶为空
 	if limiter.Allow() {
 		t.Error("第一次请求不应该被允许")
 	}
 
-	// 测试等待时间
-	start := time.Now()
-	limiter.Wait()
-	duration := time.Since(start)
+	done := make(chan error, 1)
+	go func() {
+		done <- limiter.Wait(context.Background())
+	}()
 
-	// 应该等待大约1秒
-	if duration < time.Millisecond*900 || duration > time.Millisecond*1100 {
-		t.Errorf("等待时间不符合预期，实际等待了 %v", duration)
+	// 应该等待1秒
+	fc.BlockUntil(1)
+	fc.Advance(time.Millisecond * 999)
+	select {
+	case <-done:
+		t.Fatal("不足1秒时不应返回")
+	default:
+	}
+	fc.Advance(time.Millisecond)
+	if err := <-done; err != nil {
+		t.Errorf("Wait 不应返回错误，实际错误: %v", err)
 	}
 }
 
 // 测试Return方法
 func TestReturn(t *testing.T) {
-	limiter := NewLimiter(10, 10)
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewLimiter(10, 10, WithClock(fc))
 
 	// 先获取5个令牌
 	for i := 0; i < 5; i++ {
@@ -95,7 +109,7 @@ func TestReturn(t *testing.T) {
 	}
 
 	// 等待一段时间让令牌补充一点
-	time.Sleep(time.Millisecond * 100)
+	fc.Advance(time.Millisecond * 100)
 
 	// 记录当前令牌数
 	currentTokens := limiter.tokens
@@ -134,10 +148,11 @@ func TestSetRate(t *testing.T) {
 
 // 测试SetCapacity方法
 func TestSetCapacity(t *testing.T) {
-	limiter := NewLimiter(10, 20)
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewLimiter(10, 20, WithClock(fc))
 
 	// 先充满令牌
-	time.Sleep(time.Second * 3)
+	fc.Advance(time.Second * 3)
 
 	// 缩小容量
 	newCapacity := int64(10)
@@ -155,59 +170,124 @@ func TestSetCapacity(t *testing.T) {
 // 测试WaitN方法
 func TestWaitN(t *testing.T) {
 	// 每秒2个令牌，容量5
-	limiter := NewLimiter(2, 5)
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewLimiter(2, 5, WithClock(fc))
 
-	// 测试正常情况
-	err := limiter.WaitN(context.Background(), 3)
-	if err != nil {
+	// 测试需要等待的情况：3个令牌需要1.5秒
+	done := make(chan error, 1)
+	go func() {
+		done <- limiter.WaitN(context.Background(), 3)
+	}()
+	fc.BlockUntil(1)
+	fc.Advance(time.Millisecond * 1500)
+	if err := <-done; err != nil {
 		t.Errorf("WaitN 不应返回错误，实际错误: %v", err)
 	}
 
-	// 测试需要等待的情况
-	start := time.Now()
-	err = limiter.WaitN(context.Background(), 6)
-	duration := time.Since(start)
-	if err != nil {
-		t.Errorf("WaitN 不应返回错误，实际错误: %v", err)
+	// 超过容量的请求应立即被拒绝
+	if err := limiter.WaitN(context.Background(), 6); !errors.Is(err, ErrExceedsCapacity) {
+		t.Errorf("超过容量时应返回 ErrExceedsCapacity，实际为: %v", err)
 	}
 
-	if duration < time.Second*2 || duration > time.Second*4 {
-		t.Errorf("等待时间不符合预期，实际等待了 %v", duration)
+	// 截止时间之前拿不到令牌时应立即返回
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := limiter.WaitN(ctx, 4); !errors.Is(err, ErrWouldExceedDeadline) {
+		t.Errorf("无法在截止时间前拿到令牌时应返回 ErrWouldExceedDeadline，实际为: %v", err)
 	}
 
-	// 测试上下文取消
-	ctx, cancel := context.WithCancel(context.Background())
+	// 测试上下文取消，取消后令牌应被归还
+	ctx, cancel = context.WithCancel(context.Background())
 	go func() {
-		time.Sleep(time.Millisecond * 100)
-		cancel()
+		done <- limiter.WaitN(ctx, 4)
 	}()
-
-	start = time.Now()
-	err = limiter.WaitN(ctx, 10)
-	if err == nil {
-		t.Error("WaitN 应该返回上下文取消错误")
+	fc.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitN 应该返回上下文取消错误，实际为: %v", err)
 	}
-
-	// 确保没有等待太长时间
-	duration = time.Since(start)
-	if duration > time.Millisecond*200 {
-		t.Errorf("上下文取消后应立即返回，实际等待了 %v", duration)
+	if tokens := limiter.Tokens(); tokens != 0 {
+		t.Errorf("取消后令牌应被归还，期望 0，实际 %f", tokens)
 	}
 
 	// 测试n为0的情况
-	err = limiter.WaitN(context.Background(), 0)
-	if err != nil {
+	if err := limiter.WaitN(context.Background(), 0); err != nil {
 		t.Errorf("n为0时不应返回错误，实际错误: %v", err)
 	}
 }
 
+// 测试AllowN方法
+func TestAllowN(t *testing.T) {
+	start := time.Unix(0, 0)
+	fc := clock.NewFakeClock(start)
+	limiter := NewLimiter(10, 10, WithClock(fc))
+
+	if limiter.AllowN(start.Add(time.Millisecond*500), 6) {
+		t.Error("0.5秒后只有5个令牌，不应允许6个")
+	}
+	if !limiter.AllowN(start.Add(time.Millisecond*500), 5) {
+		t.Error("0.5秒后应允许5个令牌")
+	}
+	// 时间倒退不应补充令牌
+	if limiter.AllowN(start, 1) {
+		t.Error("时间倒退时不应产生新令牌")
+	}
+	if limiter.AllowN(start.Add(time.Hour), 11) {
+		t.Error("超过容量的请求不应被允许")
+	}
+}
+
+// 测试ReserveN和Cancel
+func TestReserveN(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewLimiter(10, 10, WithClock(fc))
+
+	r1 := limiter.ReserveN(5)
+	if !r1.OK() || r1.Delay() != time.Millisecond*500 {
+		t.Errorf("第一次预约应等待500ms，实际 %v", r1.Delay())
+	}
+	r2 := limiter.ReserveN(5)
+	if !r2.OK() || r2.Delay() != time.Second {
+		t.Errorf("第二次预约应等待1s，实际 %v", r2.Delay())
+	}
+
+	// r1 的令牌已被 r2 的计算占用，取消 r1 不能归还
+	r1.Cancel()
+	if tokens := limiter.Tokens(); tokens != -10 {
+		t.Errorf("取消被后续预约覆盖的预约不应归还令牌，实际 %f", tokens)
+	}
+
+	// 取消最后一个预约可以全部归还
+	r2.Cancel()
+	if tokens := limiter.Tokens(); tokens != -5 {
+		t.Errorf("取消最后一个预约应归还5个令牌，实际 %f", tokens)
+	}
+	r2.Cancel() // 重复取消不应再次归还
+	if tokens := limiter.Tokens(); tokens != -5 {
+		t.Errorf("重复取消不应再次归还令牌，实际 %f", tokens)
+	}
+
+	// 预约生效后取消不会归还
+	r3 := limiter.ReserveN(1)
+	fc.Advance(r3.Delay())
+	r3.Cancel()
+	if tokens := limiter.Tokens(); tokens != 0 {
+		t.Errorf("生效后的预约不应归还令牌，实际 %f", tokens)
+	}
+
+	if r := limiter.ReserveN(11); r.OK() {
+		t.Error("超过容量的预约应失败")
+	}
+}
+
 // 测试并发情况下的限流器
 func TestConcurrentAllow(t *testing.T) {
 	// 每秒100个令牌，容量100
-	limiter := NewLimiter(100, 100)
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewLimiter(100, 100, WithClock(fc))
 
 	// 等待令牌充满
-	time.Sleep(time.Second * 2)
+	fc.Advance(time.Second * 2)
 
 	count := 0
 	ch := make(chan bool, 200)
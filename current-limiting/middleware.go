@@ -0,0 +1,255 @@
+package current_limiting
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"GoTools/clock"
+)
+
+// KeyFunc 从请求中提取限流的键，返回空字符串表示该请求不限流
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP 按客户端地址限流，只使用连接的对端地址
+func KeyByIP() KeyFunc {
+	return func(r *http.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
+
+// KeyByForwardedIP 优先使用 X-Forwarded-For 的第一个地址和 X-Real-IP，
+// 这些请求头可以被客户端伪造，只能在可信的反向代理之后使用
+func KeyByForwardedIP() KeyFunc {
+	byIP := KeyByIP()
+	return func(r *http.Request) string {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+		return byIP(r)
+	}
+}
+
+// KeyByHeader 按请求头的值限流，例如 API Key，请求头为空时不限流
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// KeyByRoute 按路由限流，优先使用路由模式（ServeMux 的 Pattern、gin 的 FullPath），没有时使用方法和路径
+func KeyByRoute() KeyFunc {
+	return func(r *http.Request) string {
+		if r.Pattern != "" {
+			return r.Pattern
+		}
+		return r.Method + " " + r.URL.Path
+	}
+}
+
+// LimiterFunc 返回键对应的限流器，可以是任意 RateLimiter
+type LimiterFunc func(key string) RateLimiter
+
+// PerKey 每个键使用 KeyedLimiter 中自己的令牌桶
+func PerKey(k *KeyedLimiter) LimiterFunc {
+	return func(key string) RateLimiter {
+		return k.Get(key)
+	}
+}
+
+// Shared 所有键共用同一个限流器，例如 RedisLimiter 实现的全局限流
+func Shared(l RateLimiter) LimiterFunc {
+	return func(string) RateLimiter {
+		return l
+	}
+}
+
+type middlewareConfig struct {
+	keyFunc  KeyFunc
+	maxWait  time.Duration
+	onReject http.HandlerFunc
+	clock    clock.Clock
+}
+
+type MiddlewareOption func(*middlewareConfig)
+
+// WithKeyFunc 设置提取键的方式，默认 KeyByIP
+func WithKeyFunc(fn KeyFunc) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.keyFunc = fn
+	}
+}
+
+// WithMaxWait 配额不足时最多等待 d，能在 d 内拿到配额的请求排队等待，否则立即拒绝，默认不等待
+// Limiter 通过预约提前知道等待时间，其他限流器调用 Wait 并以 d 作为 ctx 的超时时间
+func WithMaxWait(d time.Duration) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.maxWait = d
+	}
+}
+
+// WithRejectHandler 自定义被拒绝时的响应，调用时限流相关的响应头已经设置好，默认返回 429 和纯文本
+func WithRejectHandler(h http.HandlerFunc) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.onReject = h
+	}
+}
+
+// WithMiddlewareClock 指定排队等待使用的时间来源，测试中可传入 clock.FakeClock
+func WithMiddlewareClock(c clock.Clock) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.clock = c
+	}
+}
+
+var errRejected = errors.New("rate limit exceeded")
+
+// reserver 支持预约的限流器，可以在等待之前知道需要等待的时间
+type reserver interface {
+	// reserveWithin 预约 n 个令牌，需要等待超过 maxWait 时预约失败且不消耗令牌
+	reserveWithin(n int64, maxWait time.Duration) *Reservation
+}
+
+type rateLimitMiddleware struct {
+	limiters LimiterFunc
+	middlewareConfig
+}
+
+func newRateLimitMiddleware(limiters LimiterFunc, opts []MiddlewareOption) *rateLimitMiddleware {
+	m := &rateLimitMiddleware{
+		limiters: limiters,
+		middlewareConfig: middlewareConfig{
+			keyFunc: KeyByIP(),
+			onReject: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			},
+			clock: clock.New(),
+		},
+	}
+	for _, opt := range opts {
+		opt(&m.middlewareConfig)
+	}
+	return m
+}
+
+// HTTPMiddleware 返回 net/http 的限流中间件，limiters 按键返回限流器，KeyedLimiter 可以通过 PerKey 传入
+// 限流器实现了 QuotaReporter 时，响应带有 X-RateLimit-Limit/Remaining/Reset 头，
+// 被拒绝的请求返回 429 和 Retry-After，时间均为秒数
+func HTTPMiddleware(limiters LimiterFunc, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := newRateLimitMiddleware(limiters, opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.check(w, r, m.keyFunc(r)) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// GinMiddleware 返回 gin 的限流中间件，行为与 HTTPMiddleware 相同
+func GinMiddleware(limiters LimiterFunc, opts ...MiddlewareOption) gin.HandlerFunc {
+	m := newRateLimitMiddleware(limiters, opts)
+	return func(c *gin.Context) {
+		r := c.Request
+		if r.Pattern == "" && c.FullPath() != "" {
+			// 让 KeyByRoute 使用 gin 的路由模式，只影响提取键用的副本
+			copied := *r
+			copied.Pattern = r.Method + " " + c.FullPath()
+			r = &copied
+		}
+		if !m.check(c.Writer, c.Request, m.keyFunc(r)) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// check 检查键为 key 的请求是否放行，拒绝时已写入响应
+func (m *rateLimitMiddleware) check(w http.ResponseWriter, r *http.Request, key string) bool {
+	if key == "" {
+		return true
+	}
+	l := m.limiters(key)
+	if err := m.acquire(r.Context(), l); err != nil {
+		if r.Context().Err() != nil {
+			return false // 客户端已断开，无需响应
+		}
+		setQuotaHeaders(w.Header(), l, true)
+		m.onReject(w, r)
+		return false
+	}
+	setQuotaHeaders(w.Header(), l, false)
+	return true
+}
+
+// acquire 获取一个配额，能在 maxWait 内拿到时等待
+func (m *rateLimitMiddleware) acquire(ctx context.Context, l RateLimiter) error {
+	if m.maxWait <= 0 {
+		if l.Allow() {
+			return nil
+		}
+		return errRejected
+	}
+	if rv, ok := l.(reserver); ok {
+		// 等待时间超过上限时预约失败，被拒绝的请求不消耗令牌
+		res := rv.reserveWithin(1, m.maxWait)
+		if !res.OK() {
+			return errRejected
+		}
+		delay := res.Delay()
+		if delay <= 0 {
+			return nil
+		}
+		timer := m.clock.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+			return nil
+		case <-ctx.Done():
+			res.Cancel()
+			return ctx.Err()
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.maxWait)
+	defer cancel()
+	return l.Wait(ctx)
+}
+
+// setQuotaHeaders 限流器实现了 QuotaReporter 时设置配额相关的响应头，Reset 为配额完全恢复的秒数
+func setQuotaHeaders(h http.Header, l RateLimiter, rejected bool) {
+	reporter, ok := l.(QuotaReporter)
+	if !ok {
+		return
+	}
+	q := reporter.Quota()
+	h.Set("X-RateLimit-Limit", strconv.FormatInt(q.Limit, 10))
+	h.Set("X-RateLimit-Remaining", strconv.FormatInt(max(q.Remaining, 0), 10))
+	if q.Reset != infDuration {
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(q.Reset.Seconds())), 10))
+	}
+	if rejected && q.RetryAfter != infDuration {
+		h.Set("Retry-After", strconv.FormatInt(ceilSeconds(q.RetryAfter), 10))
+	}
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return max(int64(math.Ceil(d.Seconds())), 1)
+}
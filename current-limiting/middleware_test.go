@@ -0,0 +1,212 @@
+package current_limiting
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"GoTools/clock"
+)
+
+func serve(h http.Handler, method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewKeyedLimiter(1, 2, WithKeyedClock(fc))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := HTTPMiddleware(PerKey(limiter))(ok)
+
+	rec := serve(h, http.MethodGet, "/", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("期望200，实际 %d", rec.Code)
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "2" || rec.Header().Get("X-RateLimit-Remaining") != "1" ||
+		rec.Header().Get("X-RateLimit-Reset") != "1" {
+		t.Errorf("限流响应头错误: %v", rec.Header())
+	}
+	serve(h, http.MethodGet, "/", nil)
+
+	rec = serve(h, http.MethodGet, "/", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("期望429，实际 %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("拒绝响应头错误: %v", rec.Header())
+	}
+	// 被拒绝的请求不消耗令牌
+	if tokens := limiter.Get("10.0.0.1").Tokens(); tokens != 0 {
+		t.Errorf("被拒绝的请求不应消耗令牌，实际剩余 %f", tokens)
+	}
+
+	fc.Advance(time.Second)
+	if rec := serve(h, http.MethodGet, "/", nil); rec.Code != http.StatusOK {
+		t.Errorf("令牌恢复后期望200，实际 %d", rec.Code)
+	}
+}
+
+func TestHTTPMiddlewareWait(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewKeyedLimiter(10, 1, WithKeyedClock(fc))
+	h := HTTPMiddleware(PerKey(limiter), WithMaxWait(150*time.Millisecond), WithKeyFunc(KeyByHeader("X-API-Key")),
+		WithMiddlewareClock(fc))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	header := http.Header{"X-Api-Key": {"key"}}
+
+	serve(h, http.MethodGet, "/", header)
+	done := make(chan int, 1)
+	go func() { done <- serve(h, http.MethodGet, "/", header).Code }()
+	fc.BlockUntil(1) // 第二个请求等待100ms
+	// 第三个请求需要等待200ms，超过上限直接拒绝
+	if rec := serve(h, http.MethodGet, "/", header); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("超过最大等待时间期望429，实际 %d", rec.Code)
+	}
+	fc.Advance(100 * time.Millisecond)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("等待后期望200，实际 %d", code)
+	}
+
+	// 没有键的请求不限流
+	for i := 0; i < 5; i++ {
+		if rec := serve(h, http.MethodGet, "/", nil); rec.Code != http.StatusOK {
+			t.Fatalf("没有键的请求期望200，实际 %d", rec.Code)
+		}
+	}
+}
+
+// 并发被拒绝的请求不能扣减令牌
+func TestHTTPMiddlewareConcurrentReject(t *testing.T) {
+	for _, maxWait := range []time.Duration{0, 100 * time.Millisecond} {
+		fc := clock.NewFakeClock(time.Unix(0, 0))
+		limiter := NewKeyedLimiter(1, 2, WithKeyedClock(fc))
+		h := HTTPMiddleware(PerKey(limiter), WithMaxWait(maxWait), WithMiddlewareClock(fc))(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		serve(h, http.MethodGet, "/", nil)
+		serve(h, http.MethodGet, "/", nil)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if rec := serve(h, http.MethodGet, "/", nil); rec.Code != http.StatusTooManyRequests {
+					t.Errorf("maxWait=%v 期望429，实际 %d", maxWait, rec.Code)
+				}
+			}()
+		}
+		wg.Wait()
+		if tokens := limiter.Get("10.0.0.1").Tokens(); tokens != 0 {
+			t.Errorf("maxWait=%v 被拒绝的请求不应消耗令牌，实际剩余 %f", maxWait, tokens)
+		}
+		fc.Advance(time.Second)
+		if rec := serve(h, http.MethodGet, "/", nil); rec.Code != http.StatusOK {
+			t.Errorf("maxWait=%v 令牌恢复后期望200，实际 %d", maxWait, rec.Code)
+		}
+	}
+}
+
+// allowOnce 第一次请求放行之后全部拒绝，没有实现 QuotaReporter
+type allowOnce struct{ used bool }
+
+func (a *allowOnce) Allow() bool                              { return a.AllowN(time.Time{}, 1) }
+func (a *allowOnce) AllowN(now time.Time, n int64) bool       { ok := !a.used; a.used = true; return ok }
+func (a *allowOnce) Wait(ctx context.Context) error           { return a.WaitN(ctx, 1) }
+func (a *allowOnce) WaitN(ctx context.Context, n int64) error { return ErrWouldExceedDeadline }
+
+// 测试中间件可以使用任意 RateLimiter
+func TestMiddlewareRateLimiters(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	windows := map[string]RateLimiter{}
+	perKeyWindow := func(key string) RateLimiter {
+		if _, ok := windows[key]; !ok {
+			windows[key] = NewFixedWindow(2, time.Minute, WithClock(fc))
+		}
+		return windows[key]
+	}
+	gcra := NewGCRA(1, 1, WithClock(fc))
+	tests := []struct {
+		name       string
+		limiters   LimiterFunc
+		allowed    int
+		retryAfter string
+	}{
+		{"fixed_window", perKeyWindow, 2, "60"},
+		{"gcra", Shared(gcra), 1, "1"},
+		{"no_quota", Shared(&allowOnce{}), 1, ""},
+	}
+	for _, tt := range tests {
+		h := HTTPMiddleware(tt.limiters, WithMiddlewareClock(fc))(ok)
+		for i := 0; i < tt.allowed; i++ {
+			if rec := serve(h, http.MethodGet, "/", nil); rec.Code != http.StatusOK {
+				t.Fatalf("%s 第 %d 个请求期望200，实际 %d", tt.name, i+1, rec.Code)
+			}
+		}
+		rec := serve(h, http.MethodGet, "/", nil)
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != tt.retryAfter {
+			t.Errorf("%s 期望429和 Retry-After %q，实际 %d %v", tt.name, tt.retryAfter, rec.Code, rec.Header())
+		}
+		if tt.retryAfter != "" && rec.Header().Get("X-RateLimit-Remaining") != "0" {
+			t.Errorf("%s 期望剩余配额为 0，实际 %v", tt.name, rec.Header())
+		}
+		if tt.retryAfter == "" && rec.Header().Get("X-RateLimit-Limit") != "" {
+			t.Errorf("%s 没有实现 QuotaReporter 时不应设置限流响应头", tt.name)
+		}
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	if got := KeyByIP()(req); got != "10.0.0.1" {
+		t.Errorf("KeyByIP 期望 10.0.0.1，实际 %s", got)
+	}
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.2")
+	if got := KeyByForwardedIP()(req); got != "1.2.3.4" {
+		t.Errorf("KeyByForwardedIP 期望 1.2.3.4，实际 %s", got)
+	}
+	if got := KeyByIP()(req); got != "10.0.0.1" {
+		t.Errorf("KeyByIP 不应使用转发头，实际 %s", got)
+	}
+	if got := KeyByRoute()(req); got != "GET /items/1" {
+		t.Errorf("KeyByRoute 期望 GET /items/1，实际 %s", got)
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := NewKeyedLimiter(1, 1, WithKeyedClock(fc))
+	router := gin.New()
+	router.Use(GinMiddleware(PerKey(limiter), WithKeyFunc(KeyByRoute())))
+	router.GET("/items/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Param("id"))
+	})
+
+	if rec := serve(router, http.MethodGet, "/items/1", nil); rec.Code != http.StatusOK {
+		t.Fatalf("期望200，实际 %d", rec.Code)
+	}
+	// 同一路由的不同参数共享配额
+	rec := serve(router, http.MethodGet, "/items/2", nil)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("期望429和 Retry-After，实际 %d %v", rec.Code, rec.Header())
+	}
+	if limiter.Len() != 1 || limiter.Get("GET /items/:id").Tokens() != 0 {
+		t.Errorf("应按 gin 的路由模式限流，实际 %d 个键", limiter.Len())
+	}
+}
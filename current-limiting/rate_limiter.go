@@ -0,0 +1,126 @@
+package current_limiting
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"GoTools/clock"
+)
+
+var ErrUnknownAlgorithm = errors.New("unknown rate limit algorithm")
+
+// RateLimiter 限流器的通用接口，Limiter、RedisLimiter 以及各种窗口、漏桶、GCRA 限流器都实现了该接口
+type RateLimiter interface {
+	Allow() bool
+	AllowN(now time.Time, n int64) bool
+	Wait(ctx context.Context) error
+	WaitN(ctx context.Context, n int64) error
+}
+
+var (
+	_ RateLimiter = (*Limiter)(nil)
+	_ RateLimiter = (*RedisLimiter)(nil)
+	_ RateLimiter = (*FixedWindow)(nil)
+	_ RateLimiter = (*SlidingWindow)(nil)
+	_ RateLimiter = (*LeakyBucket)(nil)
+	_ RateLimiter = (*GCRA)(nil)
+
+	_ QuotaReporter = (*Limiter)(nil)
+	_ QuotaReporter = (*FixedWindow)(nil)
+	_ QuotaReporter = (*SlidingWindow)(nil)
+	_ QuotaReporter = (*LeakyBucket)(nil)
+	_ QuotaReporter = (*GCRA)(nil)
+)
+
+// Quota 限流器当前的配额状态，无法恢复的时间为 math.MaxInt64
+type Quota struct {
+	Limit      int64         // 配额上限
+	Remaining  int64         // 还能立即放行的请求数
+	Reset      time.Duration // 配额完全恢复还需的时间
+	RetryAfter time.Duration // 下一个请求可以放行还需的时间，Remaining 大于 0 时为 0
+}
+
+// QuotaReporter 可以报告配额状态的限流器，HTTP 中间件用它设置 X-RateLimit-* 响应头
+type QuotaReporter interface {
+	Quota() Quota
+}
+
+// Algorithm 限流算法名称，可直接写在配置文件中
+type Algorithm string
+
+const (
+	AlgorithmTokenBucket   Algorithm = "token_bucket"
+	AlgorithmFixedWindow   Algorithm = "fixed_window"
+	AlgorithmSlidingWindow Algorithm = "sliding_window"
+	AlgorithmLeakyBucket   Algorithm = "leaky_bucket"
+	AlgorithmGCRA          Algorithm = "gcra"
+)
+
+// Config 限流配置，不同算法使用的字段不同
+type Config struct {
+	Algorithm Algorithm     `json:"algorithm" yaml:"algorithm"`
+	Rate      int64         `json:"rate" yaml:"rate"`     // 令牌桶、漏桶、GCRA：每秒允许的请求数
+	Burst     int64         `json:"burst" yaml:"burst"`   // 令牌桶容量、漏桶队列长度、GCRA 允许的突发数
+	Limit     int64         `json:"limit" yaml:"limit"`   // 窗口算法：每个窗口允许的请求数
+	Window    time.Duration `json:"window" yaml:"window"` // 窗口算法：窗口长度
+}
+
+// NewRateLimiter 按配置创建限流器，Algorithm 为空时使用令牌桶
+func NewRateLimiter(cfg Config, opts ...Option) (RateLimiter, error) {
+	switch cfg.Algorithm {
+	case AlgorithmTokenBucket, "":
+		return NewLimiter(cfg.Rate, cfg.Burst, opts...), nil
+	case AlgorithmFixedWindow:
+		if cfg.Window <= 0 {
+			return nil, errors.New("window must be greater than 0")
+		}
+		return NewFixedWindow(cfg.Limit, cfg.Window, opts...), nil
+	case AlgorithmSlidingWindow:
+		if cfg.Window <= 0 {
+			return nil, errors.New("window must be greater than 0")
+		}
+		return NewSlidingWindow(cfg.Limit, cfg.Window, opts...), nil
+	case AlgorithmLeakyBucket:
+		if cfg.Rate <= 0 {
+			return nil, errors.New("rate must be greater than 0")
+		}
+		return NewLeakyBucket(cfg.Rate, cfg.Burst, opts...), nil
+	case AlgorithmGCRA:
+		if cfg.Rate <= 0 {
+			return nil, errors.New("rate must be greater than 0")
+		}
+		return NewGCRA(cfg.Rate, cfg.Burst, opts...), nil
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+}
+
+// waitUntilAllowed 循环调用 try 直到拿到配额、ctx 结束或 ctx 的截止时间早于配额可用时间
+// try 在配额不足时不能消耗配额，并返回还需等待的时间
+func waitUntilAllowed(ctx context.Context, clk clock.Clock, try func(now time.Time) (time.Duration, bool)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		wait, ok := try(clk.Now())
+		if ok {
+			return nil
+		}
+		if wait == infDuration {
+			return ErrWouldExceedDeadline
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return ErrWouldExceedDeadline
+		}
+
+		// 醒来后配额可能已被其他请求抢走，需要重新尝试
+		timer := clk.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package current_limiting
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"GoTools/clock"
+)
+
+// 通过配置创建各种限流器
+func TestNewRateLimiter(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	tests := []struct {
+		cfg  Config
+		want any
+	}{
+		{Config{Rate: 10, Burst: 10}, &Limiter{}},
+		{Config{Algorithm: AlgorithmFixedWindow, Limit: 10, Window: time.Second}, &FixedWindow{}},
+		{Config{Algorithm: AlgorithmSlidingWindow, Limit: 10, Window: time.Second}, &SlidingWindow{}},
+		{Config{Algorithm: AlgorithmLeakyBucket, Rate: 10, Burst: 10}, &LeakyBucket{}},
+		{Config{Algorithm: AlgorithmGCRA, Rate: 10, Burst: 10}, &GCRA{}},
+	}
+	for _, tt := range tests {
+		l, err := NewRateLimiter(tt.cfg, WithClock(fc))
+		if err != nil {
+			t.Fatalf("%s: 创建失败: %v", tt.cfg.Algorithm, err)
+		}
+		if got, want := typeName(l), typeName(tt.want); got != want {
+			t.Errorf("%s: 期望 %s，实际 %s", tt.cfg.Algorithm, want, got)
+		}
+	}
+
+	if _, err := NewRateLimiter(Config{Algorithm: "unknown"}); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("期望 ErrUnknownAlgorithm，实际 %v", err)
+	}
+	if _, err := NewRateLimiter(Config{Algorithm: AlgorithmFixedWindow, Limit: 10}); err == nil {
+		t.Error("窗口长度为0应返回错误")
+	}
+	if _, err := NewRateLimiter(Config{Algorithm: AlgorithmGCRA}); err == nil {
+		t.Error("速率为0应返回错误")
+	}
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case *Limiter:
+		return "Limiter"
+	case *FixedWindow:
+		return "FixedWindow"
+	case *SlidingWindow:
+		return "SlidingWindow"
+	case *LeakyBucket:
+		return "LeakyBucket"
+	case *GCRA:
+		return "GCRA"
+	}
+	return "unknown"
+}
+
+// 统计 duration 时间内每隔 step 尝试 perStep 次时允许的请求数
+func countAllowed(fc *clock.FakeClock, l RateLimiter, duration, step time.Duration, perStep int) int {
+	count := 0
+	for elapsed := time.Duration(0); elapsed < duration; elapsed += step {
+		for i := 0; i < perStep; i++ {
+			if l.Allow() {
+				count++
+			}
+		}
+		fc.Advance(step)
+	}
+	return count
+}
+
+func TestFixedWindow(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	w := NewFixedWindow(5, time.Second, WithClock(fc))
+
+	if got := countAllowed(fc, w, time.Second, 100*time.Millisecond, 10); got != 5 {
+		t.Errorf("一个窗口内期望允许5个请求，实际 %d 个", got)
+	}
+	if w.Remaining() != 5 || !w.Reset().Equal(time.Unix(2, 0)) {
+		t.Errorf("新窗口剩余 %d，重置时间 %v", w.Remaining(), w.Reset())
+	}
+
+	// 窗口交界处可以放过 2*limit 个请求
+	fc.Advance(900 * time.Millisecond)
+	count := countAllowed(fc, w, 200*time.Millisecond, 100*time.Millisecond, 10)
+	if count != 10 {
+		t.Errorf("窗口交界处期望允许10个请求，实际 %d 个", count)
+	}
+	if w.AllowN(fc.Now(), 6) {
+		t.Error("超过 limit 的请求不应被允许")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	w := NewSlidingWindow(10, time.Second, WithClock(fc))
+
+	if !w.AllowN(fc.Now(), 10) || w.Allow() {
+		t.Fatal("第一个窗口应恰好允许10个请求")
+	}
+
+	// 进入下一个窗口 30%，上一个窗口还占 70%，估算为7
+	fc.Advance(1300 * time.Millisecond)
+	if got := w.Remaining(); got != 3 {
+		t.Errorf("期望剩余3个，实际 %d 个", got)
+	}
+	if !w.AllowN(fc.Now(), 3) || w.Allow() {
+		t.Error("应恰好再允许3个请求")
+	}
+
+	// 固定窗口交界处的突发在滑动窗口中被平滑
+	w2 := NewSlidingWindow(10, time.Second, WithClock(fc))
+	fc.Advance(time.Second - time.Duration(fc.Now().UnixNano())%time.Second - 100*time.Millisecond)
+	first := countAllowed(fc, w2, 100*time.Millisecond, 100*time.Millisecond, 20)
+	fc.Advance(100 * time.Millisecond) // 新窗口的 10%，上一个窗口的估算为9
+	second := countAllowed(fc, w2, 100*time.Millisecond, 100*time.Millisecond, 20)
+	if first != 10 || second != 1 {
+		t.Errorf("窗口交界处期望允许10+1个请求，实际 %d+%d 个", first, second)
+	}
+}
+
+func TestLeakyBucket(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	b := NewLeakyBucket(10, 3, WithClock(fc))
+
+	// 不允许突发：每100ms只能流出一个
+	if got := countAllowed(fc, b, time.Second, 50*time.Millisecond, 5); got != 10 {
+		t.Errorf("一秒内期望流出10个请求，实际 %d 个", got)
+	}
+
+	// 排队：3个请求依次在0、100、200ms流出
+	ctx := context.Background()
+	done := make(chan time.Time, 4)
+	for i := 0; i < 3; i++ {
+		go func() {
+			if err := b.Wait(ctx); err != nil {
+				t.Errorf("排队失败: %v", err)
+			}
+			done <- fc.Now()
+		}()
+	}
+	<-done // 第一个请求不需要等待
+	fc.BlockUntil(2)
+	if got := b.Queued(); got != 2 {
+		t.Errorf("期望排队2个，实际 %d 个", got)
+	}
+	if err := b.WaitN(ctx, 2); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("期望 ErrQueueFull，实际 %v", err)
+	}
+	fc.Advance(100 * time.Millisecond)
+	<-done
+	fc.Advance(100 * time.Millisecond)
+	<-done
+	if b.Queued() != 0 {
+		t.Errorf("队列应已清空，实际 %d 个", b.Queued())
+	}
+
+	// 取消最后一个排队的请求会让出位置
+	fc.Advance(100 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("队列为空时应允许请求")
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() { errCh <- b.Wait(cctx) }()
+	fc.BlockUntil(1)
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("期望 context.Canceled，实际 %v", err)
+	}
+	if b.Queued() != 0 {
+		t.Errorf("取消后队列应为空，实际 %d 个", b.Queued())
+	}
+	if b.Allow() {
+		t.Error("正在流出的请求占用的间隔内不应允许新请求")
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	g := NewGCRA(10, 5, WithClock(fc))
+
+	// 初始允许突发5个
+	if !g.AllowN(fc.Now(), 5) || g.Allow() {
+		t.Fatal("期望恰好允许突发5个请求")
+	}
+	if g.Remaining() != 0 {
+		t.Errorf("期望剩余0个，实际 %d 个", g.Remaining())
+	}
+	// 之后按每100ms一个的速率恢复
+	fc.Advance(100 * time.Millisecond)
+	if !g.Allow() || g.Allow() {
+		t.Error("100ms后应恰好允许1个请求")
+	}
+	fc.Advance(time.Second)
+	if g.Remaining() != 5 {
+		t.Errorf("空闲后应恢复到突发上限，实际 %d 个", g.Remaining())
+	}
+	if g.AllowN(fc.Now(), 6) {
+		t.Error("超过 burst 的请求不应被允许")
+	}
+
+	// 突发用完后每100ms只放行一个
+	if got := countAllowed(fc, g, 10*time.Second, 10*time.Millisecond, 1); got != 104 {
+		t.Errorf("10秒内期望允许104个请求（突发5个+之后99个），实际 %d 个", got)
+	}
+}
+
+// n <= 0 时不允许，也不能把配额加回来
+func TestNonPositiveN(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer client.Close()
+	limiters := map[string]RateLimiter{
+		"token_bucket":   NewLimiter(1, 2, WithClock(fc), WithInitialTokens(2)),
+		"fixed_window":   NewFixedWindow(2, time.Minute, WithClock(fc)),
+		"sliding_window": NewSlidingWindow(2, time.Minute, WithClock(fc)),
+		"gcra":           NewGCRA(1, 2, WithClock(fc)),
+		"leaky_bucket":   NewLeakyBucket(1, 2, WithClock(fc)),
+		"redis":          NewRedisTokenBucket(client, "unreachable", 1, 2), // 降级到满桶的本地限流器
+	}
+	for name, l := range limiters {
+		if !l.AllowN(fc.Now(), 2) {
+			t.Fatalf("%s 应允许 2 个请求", name)
+		}
+		if l.AllowN(fc.Now(), 0) || l.AllowN(fc.Now(), -5) {
+			t.Errorf("%s n <= 0 时应返回 false", name)
+		}
+		if l.Allow() {
+			t.Errorf("%s 负数不应把配额加回来", name)
+		}
+	}
+	if b := NewLeakyBucket(1, 5, WithClock(fc)); b.AllowN(fc.Now(), 100) || b.Queued() != 0 {
+		t.Errorf("漏桶超过队列容量时应拒绝，实际排队 %d 个", b.Queued())
+	}
+}
+
+// 速率为 0 时创建失败，速率超过每纳秒一个时间隔不会变成 0
+func TestEmissionRate(t *testing.T) {
+	for name, create := range map[string]func(){
+		"leaky_bucket": func() { NewLeakyBucket(0, 1) },
+		"gcra":         func() { NewGCRA(0, 1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s 速率为 0 时应 panic", name)
+				}
+			}()
+			create()
+		}()
+	}
+	if _, err := NewRateLimiter(Config{Algorithm: AlgorithmGCRA}); err == nil {
+		t.Error("按配置创建速率为 0 的 GCRA 应返回错误")
+	}
+
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	b := NewLeakyBucket(2e9, 3, WithClock(fc))
+	if !b.Allow() || b.Queued() != 0 || b.Quota().Remaining != 3 {
+		t.Error("速率很高的漏桶应正常工作")
+	}
+	g := NewGCRA(2e9, 3, WithClock(fc))
+	if !g.AllowN(fc.Now(), 3) || g.Allow() || g.Remaining() != 0 {
+		t.Error("速率很高的 GCRA 应允许突发 3 个")
+	}
+	fc.Advance(3 * time.Nanosecond)
+	if g.Remaining() != 3 {
+		t.Errorf("3ns 后应恢复突发上限，实际 %d 个", g.Remaining())
+	}
+}
+
+// 所有实现共用的等待行为
+func TestRateLimiterWait(t *testing.T) {
+	configs := []Config{
+		{Algorithm: AlgorithmTokenBucket, Rate: 10, Burst: 1},
+		{Algorithm: AlgorithmFixedWindow, Limit: 1, Window: 100 * time.Millisecond},
+		{Algorithm: AlgorithmSlidingWindow, Limit: 1, Window: 100 * time.Millisecond},
+		{Algorithm: AlgorithmLeakyBucket, Rate: 10, Burst: 1},
+		{Algorithm: AlgorithmGCRA, Rate: 10, Burst: 1},
+	}
+	for _, cfg := range configs {
+		t.Run(string(cfg.Algorithm), func(t *testing.T) {
+			fc := clock.NewFakeClock(time.Unix(0, 0))
+			l, err := NewRateLimiter(cfg, WithClock(fc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := l.WaitN(context.Background(), 2); !errors.Is(err, ErrExceedsCapacity) {
+				t.Errorf("期望 ErrExceedsCapacity，实际 %v", err)
+			}
+
+			fc.Advance(time.Second) // 令牌桶初始为空
+			l.Allow()
+			errCh := make(chan error, 1)
+			go func() { errCh <- l.Wait(context.Background()) }()
+			fc.BlockUntil(1)
+			select {
+			case err := <-errCh:
+				t.Fatalf("配额用完时 Wait 不应立即返回: %v", err)
+			default:
+			}
+			fc.Advance(200 * time.Millisecond) // 滑动窗口需要等上一个窗口的权重完全衰减
+			if err := <-errCh; err != nil {
+				t.Errorf("等待失败: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+				t.Errorf("期望 context.Canceled，实际 %v", err)
+			}
+		})
+	}
+}
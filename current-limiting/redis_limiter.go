@@ -0,0 +1,292 @@
+package current_limiting
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"GoTools/clock"
+)
+
+// 令牌桶脚本，时间取 Redis 服务端的 TIME，避免各实例时钟不一致
+// KEYS[1] 桶的哈希键；ARGV[1] 每秒生成的令牌数；ARGV[2] 容量；ARGV[3] 请求的令牌数
+// 返回 {是否允许, 还需等待的微秒数（-1 表示永远拿不到）, 剩余令牌数}
+// 键不存在时视为桶已满：键只会在空闲足够久（足以补满令牌）后过期
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate / 1000000)
+end
+
+local allowed = 0
+local wait = 0
+if tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+elseif rate <= 0 or n > capacity then
+	wait = -1
+else
+	wait = math.ceil((n - tokens) * 1000000 / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+if rate > 0 then
+	redis.call("PEXPIRE", KEYS[1], math.ceil(capacity * 1000 / rate) + 1000)
+end
+return {allowed, wait, math.floor(tokens)}`)
+
+// 滑动窗口日志脚本，有序集合中每个成员是一次请求，分数为请求时间
+// KEYS[1] 有序集合键；ARGV[1] 窗口内允许的请求数；ARGV[2] 窗口长度（微秒）；ARGV[3] 请求数；ARGV[4] 成员前缀
+// 返回 {是否允许, 还需等待的微秒数, 剩余配额}
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+if count + n <= limit then
+	for i = 1, n do
+		redis.call("ZADD", KEYS[1], now, ARGV[4] .. ":" .. i)
+	end
+	redis.call("PEXPIRE", KEYS[1], math.ceil(window / 1000))
+	return {1, 0, limit - count - n}
+end
+if n > limit then
+	return {0, -1, limit - count}
+end
+
+-- 需要等到最早的 count+n-limit 个请求移出窗口
+local oldest = redis.call("ZRANGE", KEYS[1], count + n - limit - 1, count + n - limit - 1, "WITHSCORES")
+local wait = tonumber(oldest[2]) + window - now
+if wait < 1 then
+	wait = 1
+end
+return {0, wait, limit - count}`)
+
+// RedisLimiter 基于 Redis 的分布式限流器，多个实例共享同一个键即可实现全局限流
+// 判断和扣减在 Lua 脚本中原子完成，Redis 不可用时降级到本地限流器
+type RedisLimiter struct {
+	key      string
+	limit    int64 // 令牌桶为容量，滑动窗口为窗口内允许的请求数
+	eval     func(ctx context.Context, n int64) ([]any, error)
+	fallback *Limiter
+	timeout  time.Duration // 单次访问 Redis 的超时时间，0 表示不设置
+	clock    clock.Clock
+	nonce    string        // 实例标识，保证滑动窗口中的成员唯一
+	seq      atomic.Uint64 // 实例内的请求序号
+	degraded atomic.Uint64 // 降级到本地限流器的次数
+}
+
+type RedisOption func(*RedisLimiter)
+
+// WithFallback 指定 Redis 不可用时使用的本地限流器
+// 默认使用与全局配置相同、创建时已满的本地限流器，多副本部署时通常需要按副本数缩小
+func WithFallback(l *Limiter) RedisOption {
+	return func(r *RedisLimiter) {
+		r.fallback = l
+	}
+}
+
+// WithRedisTimeout 设置单次访问 Redis 的超时时间，超时视为 Redis 不可用，默认 100ms，0 表示不设置
+// 只有客户端开启 ContextTimeoutEnabled 时生效，否则由客户端的 ReadTimeout 和 WriteTimeout 限制
+func WithRedisTimeout(d time.Duration) RedisOption {
+	return func(r *RedisLimiter) {
+		r.timeout = d
+	}
+}
+
+// WithRedisClock 指定 Wait 等待使用的时间来源，令牌的计算始终以 Redis 服务端时间为准
+func WithRedisClock(c clock.Clock) RedisOption {
+	return func(r *RedisLimiter) {
+		r.clock = c
+	}
+}
+
+// NewRedisTokenBucket 创建基于令牌桶的分布式限流器，每秒生成 rate 个令牌，最多积攒 capacity 个
+func NewRedisTokenBucket(client *redis.Client, key string, rate, capacity int64, opts ...RedisOption) *RedisLimiter {
+	r := newRedisLimiter(key, capacity)
+	r.eval = func(ctx context.Context, n int64) ([]any, error) {
+		return tokenBucketScript.Run(ctx, client, []string{key}, rate, capacity, n).Slice()
+	}
+	// 脚本把不存在的键视为满桶，降级时同样从满桶开始
+	r.fallback = NewLimiter(rate, capacity, WithInitialTokens(capacity))
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// NewRedisSlidingWindow 创建基于滑动窗口日志的分布式限流器，任意 window 时间内最多允许 limit 个请求
+// 每个请求在有序集合中占一个成员，适合 limit 不太大的场景
+func NewRedisSlidingWindow(client *redis.Client, key string, limit int64, window time.Duration, opts ...RedisOption) *RedisLimiter {
+	r := newRedisLimiter(key, limit)
+	r.eval = func(ctx context.Context, n int64) ([]any, error) {
+		member := r.nonce + ":" + strconv.FormatUint(r.seq.Add(1), 10)
+		return slidingWindowScript.Run(ctx, client, []string{key}, limit, window.Microseconds(), n, member).Slice()
+	}
+	// 本地降级时用等效速率的令牌桶近似
+	rate := int64(float64(limit) / window.Seconds())
+	r.fallback = NewLimiter(max(rate, 1), limit, WithInitialTokens(limit))
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func newRedisLimiter(key string, limit int64) *RedisLimiter {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return &RedisLimiter{
+		key:     key,
+		limit:   limit,
+		timeout: 100 * time.Millisecond,
+		clock:   clock.New(),
+		nonce:   hex.EncodeToString(b),
+	}
+}
+
+// Allow 判断是否允许一个请求
+func (r *RedisLimiter) Allow() bool {
+	res, err := r.reserve(context.Background(), 1)
+	if err != nil {
+		r.degraded.Add(1)
+		return r.fallback.Allow() // 本地限流器使用自己的时间来源
+	}
+	return res.allowed
+}
+
+// AllowN 判断是否有 n 个配额可用，有则立即消耗
+// Redis 中以服务端时间为准，now 只在降级到本地限流器时使用
+func (r *RedisLimiter) AllowN(now time.Time, n int64) bool {
+	if n <= 0 {
+		return false
+	}
+	res, err := r.reserve(context.Background(), n)
+	if err != nil {
+		r.degraded.Add(1)
+		return r.fallback.AllowN(now, n)
+	}
+	return res.allowed
+}
+
+// Wait 阻塞直到拿到一个配额或 ctx 结束
+func (r *RedisLimiter) Wait(ctx context.Context) error {
+	return r.WaitN(ctx, 1)
+}
+
+// WaitN 阻塞直到拿到 n 个配额或 ctx 结束
+// n 超过上限时返回 ErrExceedsCapacity，ctx 的截止时间早于配额可用时间时返回 ErrWouldExceedDeadline
+func (r *RedisLimiter) WaitN(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	if n > r.limit {
+		return ErrExceedsCapacity
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		res, err := r.reserve(ctx, n)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			r.degraded.Add(1)
+			return r.fallback.WaitN(ctx, n)
+		}
+		if res.allowed {
+			return nil
+		}
+		if res.wait == infDuration {
+			return ErrWouldExceedDeadline
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < res.wait {
+			return ErrWouldExceedDeadline
+		}
+
+		// 其他实例可能在等待期间抢走配额，醒来后重新尝试
+		timer := r.clock.NewTimer(res.wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Remaining 返回最近一次访问后剩余的配额，不消耗配额，Redis 不可用时返回本地限流器的令牌数
+func (r *RedisLimiter) Remaining(ctx context.Context) (int64, error) {
+	res, err := r.reserve(ctx, 0)
+	if err != nil {
+		return int64(r.fallback.Tokens()), err
+	}
+	return res.remaining, nil
+}
+
+// Degraded 返回因 Redis 不可用而降级到本地限流器的次数
+func (r *RedisLimiter) Degraded() uint64 {
+	return r.degraded.Load()
+}
+
+// Key 返回限流器在 Redis 中使用的键
+func (r *RedisLimiter) Key() string {
+	return r.key
+}
+
+type redisResult struct {
+	allowed   bool
+	wait      time.Duration
+	remaining int64
+}
+
+// reserve 执行限流脚本，n 为 0 时只查询不消耗
+// 超时由 go-redis 根据 ctx 的截止时间控制，客户端需要开启 ContextTimeoutEnabled
+func (r *RedisLimiter) reserve(ctx context.Context, n int64) (redisResult, error) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	vals, err := r.eval(ctx, n)
+	if err != nil {
+		return redisResult{}, err
+	}
+	if len(vals) != 3 {
+		return redisResult{}, errors.New("unexpected redis limiter script result")
+	}
+	allowed, _ := vals[0].(int64)
+	wait, _ := vals[1].(int64)
+	remaining, _ := vals[2].(int64)
+	res := redisResult{allowed: allowed == 1, remaining: remaining}
+	if wait < 0 {
+		res.wait = infDuration
+	} else {
+		res.wait = time.Duration(wait) * time.Microsecond
+	}
+	return res, nil
+}
@@ -0,0 +1,204 @@
+package current_limiting
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"GoTools/clock"
+)
+
+// 连接本地Redis，Redis 不可用时跳过测试
+func newTestRedis(t *testing.T, key string) *redis.Client {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("本地Redis不可用: %v", err)
+	}
+	client.Del(context.Background(), key)
+	t.Cleanup(func() {
+		client.Del(context.Background(), key)
+		client.Close()
+	})
+	return client
+}
+
+// 测试令牌桶：多个实例共享同一个桶
+func TestRedisTokenBucket(t *testing.T) {
+	key := "test_redis_token_bucket"
+	client := newTestRedis(t, key)
+
+	a := NewRedisTokenBucket(client, key, 1, 5)
+	b := NewRedisTokenBucket(client, key, 1, 5)
+
+	// 初始桶是满的，两个实例一共只能拿到5个
+	count := 0
+	for i := 0; i < 5; i++ {
+		if a.Allow() {
+			count++
+		}
+		if b.Allow() {
+			count++
+		}
+	}
+	if count != 5 {
+		t.Errorf("期望允许5个请求，实际允许了 %d 个", count)
+	}
+	if a.AllowN(time.Now(), 6) {
+		t.Error("超过容量的请求不应被允许")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := a.Wait(ctx); err != nil {
+		t.Fatalf("等待令牌失败: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("等待时间过短: %v", elapsed)
+	}
+	if err := a.WaitN(ctx, 6); !errors.Is(err, ErrExceedsCapacity) {
+		t.Errorf("期望 ErrExceedsCapacity，实际 %v", err)
+	}
+
+	short, cancel2 := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel2()
+	if err := b.Wait(short); !errors.Is(err, ErrWouldExceedDeadline) {
+		t.Errorf("期望 ErrWouldExceedDeadline，实际 %v", err)
+	}
+	if a.Degraded() != 0 {
+		t.Errorf("Redis 可用时不应降级，实际降级 %d 次", a.Degraded())
+	}
+}
+
+// 测试滑动窗口
+func TestRedisSlidingWindow(t *testing.T) {
+	key := "test_redis_sliding_window"
+	client := newTestRedis(t, key)
+
+	limiter := NewRedisSlidingWindow(client, key, 3, 500*time.Millisecond)
+	if !limiter.AllowN(time.Now(), 2) || !limiter.Allow() {
+		t.Fatal("窗口内前3个请求应被允许")
+	}
+	if limiter.Allow() {
+		t.Error("窗口已满，请求不应被允许")
+	}
+	if remaining, err := limiter.Remaining(context.Background()); err != nil || remaining != 0 {
+		t.Errorf("期望剩余0，实际 %d, %v", remaining, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("等待窗口滑动失败: %v", err)
+	}
+	if limiter.AllowN(time.Now(), 4) {
+		t.Error("超过窗口上限的请求不应被允许")
+	}
+}
+
+// 测试 Redis 不可用时降级到本地限流器
+func TestRedisLimiterFallback(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1", // 不可达的地址
+		DialTimeout: 50 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+	defer client.Close()
+
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	local := NewLimiter(10, 2, WithClock(fc))
+	limiter := NewRedisTokenBucket(client, "unreachable", 100, 100,
+		WithFallback(local), WithRedisTimeout(100*time.Millisecond))
+
+	if limiter.Allow() {
+		t.Error("本地限流器初始没有令牌，不应允许")
+	}
+	fc.Advance(time.Second)
+	if !limiter.AllowN(fc.Now(), 2) {
+		t.Error("降级后应使用本地限流器的令牌")
+	}
+	if limiter.Allow() {
+		t.Error("本地令牌已用完，不应允许")
+	}
+	if limiter.Degraded() != 3 {
+		t.Errorf("期望降级3次，实际 %d 次", limiter.Degraded())
+	}
+	if _, err := limiter.Remaining(context.Background()); err == nil {
+		t.Error("Redis 不可用时 Remaining 应返回错误")
+	}
+}
+
+// 默认的本地限流器与脚本一致，从满桶开始
+func TestRedisLimiterDefaultFallback(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: 50 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+	defer client.Close()
+
+	for name, limiter := range map[string]*RedisLimiter{
+		"token_bucket":   NewRedisTokenBucket(client, "unreachable", 1, 3),
+		"sliding_window": NewRedisSlidingWindow(client, "unreachable", 3, time.Minute),
+	} {
+		for i := 0; i < 3; i++ {
+			if !limiter.Allow() {
+				t.Fatalf("%s 降级后第 %d 个请求应被允许", name, i+1)
+			}
+		}
+		if limiter.Allow() {
+			t.Errorf("%s 突发用完后不应允许", name)
+		}
+	}
+}
+
+// 测试 Redis 接受连接但不响应时，按默认超时及时降级
+func TestRedisLimiterStalled(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conns []net.Conn
+	var mu sync.Mutex
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn) // 只接受连接，不读也不响应
+			mu.Unlock()
+		}
+	}()
+	client := redis.NewClient(&redis.Options{Addr: ln.Addr().String(), MaxRetries: -1, ContextTimeoutEnabled: true})
+	defer func() {
+		ln.Close()
+		mu.Lock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+		client.Close()
+	}()
+
+	limiter := NewRedisTokenBucket(client, "stalled", 1, 1)
+	start := time.Now()
+	if !limiter.Allow() {
+		t.Error("降级后应使用本地限流器的令牌")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Redis 不响应时应在默认超时后降级，实际等待 %v", elapsed)
+	}
+	if limiter.Degraded() != 1 {
+		t.Errorf("期望降级1次，实际 %d 次", limiter.Degraded())
+	}
+}
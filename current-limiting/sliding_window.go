@@ -0,0 +1,141 @@
+package current_limiting
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"GoTools/clock"
+)
+
+// SlidingWindow 滑动窗口计数器，用上一个窗口的计数按重叠比例加权估算最近 window 内的请求数
+// 只保存两个计数器，解决了固定窗口交界处的突发问题
+type SlidingWindow struct {
+	mu     sync.Mutex
+	limit  int64
+	window time.Duration
+	start  time.Time // 当前窗口的开始时间
+	prev   int64     // 上一个窗口的请求数
+	curr   int64     // 当前窗口的请求数
+	clock  clock.Clock
+}
+
+func NewSlidingWindow(limit int64, window time.Duration, opts ...Option) *SlidingWindow {
+	o := newOptions(opts)
+	return &SlidingWindow{
+		limit:  limit,
+		window: window,
+		start:  o.clock.Now().Truncate(window),
+		clock:  o.clock,
+	}
+}
+
+func (w *SlidingWindow) Allow() bool {
+	return w.AllowN(w.clock.Now(), 1)
+}
+
+// AllowN 判断最近一个窗口内是否还能容纳 n 个请求，能则计入，n <= 0 时返回 false
+func (w *SlidingWindow) AllowN(now time.Time, n int64) bool {
+	if n <= 0 {
+		return false // 负数会把配额加回窗口
+	}
+	_, ok := w.tryN(now, n)
+	return ok
+}
+
+func (w *SlidingWindow) Wait(ctx context.Context) error {
+	return w.WaitN(ctx, 1)
+}
+
+// WaitN 等待直到最近一个窗口能容纳 n 个请求，n 超过 limit 时返回 ErrExceedsCapacity
+func (w *SlidingWindow) WaitN(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	if n > w.limit {
+		return ErrExceedsCapacity
+	}
+	return waitUntilAllowed(ctx, w.clock, func(now time.Time) (time.Duration, bool) {
+		return w.tryN(now, n)
+	})
+}
+
+// Remaining 返回估算的剩余请求数
+func (w *SlidingWindow) Remaining() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+	w.advance(now)
+	return max(w.limit-int64(math.Ceil(w.estimate(now))), 0)
+}
+
+// Quota 返回估算的配额状态，当前窗口的计数在下一个窗口结束时完全移出
+func (w *SlidingWindow) Quota() Quota {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+	w.advance(now)
+	q := Quota{Limit: w.limit, Remaining: max(w.limit-int64(math.Ceil(w.estimate(now))), 0)}
+	switch {
+	case w.curr > 0:
+		q.Reset = w.start.Add(2 * w.window).Sub(now)
+	case w.prev > 0:
+		q.Reset = w.start.Add(w.window).Sub(now)
+	}
+	q.RetryAfter = w.waitN(now, 1)
+	return q
+}
+
+// tryN 尝试计入 n 个请求，失败时返回估算值降到可以容纳 n 个请求还需等待的时间
+func (w *SlidingWindow) tryN(now time.Time, n int64) (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.advance(now)
+	wait := w.waitN(now, n)
+	if wait == 0 {
+		w.curr += n
+		return 0, true
+	}
+	return wait, false
+}
+
+// waitN 返回还需等待多久才能容纳 n 个请求，可以立即容纳时返回 0，调用方需持有锁并已进入 now 所在的窗口
+func (w *SlidingWindow) waitN(now time.Time, n int64) time.Duration {
+	if w.estimate(now)+float64(n) <= float64(w.limit) {
+		return 0
+	}
+	if n > w.limit {
+		return infDuration
+	}
+	end := w.start.Add(w.window)
+	free := w.limit - w.curr - n
+	if free < 0 || w.prev == 0 {
+		return end.Sub(now) // 当前窗口内不可能满足，等到下一个窗口重新计算
+	}
+	// prev*(1-elapsed/window) <= free 时可以满足
+	elapsed := time.Duration(math.Ceil(float64(w.window) * (1 - float64(free)/float64(w.prev))))
+	wait := w.start.Add(elapsed).Sub(now)
+	return min(max(wait, time.Nanosecond), end.Sub(now))
+}
+
+// estimate 估算 now 之前一个窗口内的请求数，调用方需持有锁
+func (w *SlidingWindow) estimate(now time.Time) float64 {
+	weight := 1 - float64(now.Sub(w.start))/float64(w.window)
+	return float64(w.prev)*weight + float64(w.curr)
+}
+
+// advance 进入 now 所在的窗口，调用方需持有锁
+func (w *SlidingWindow) advance(now time.Time) {
+	elapsed := now.Sub(w.start)
+	if elapsed < w.window {
+		return
+	}
+	if elapsed < 2*w.window {
+		w.prev = w.curr // 相邻窗口，当前计数成为上一个窗口的计数
+	} else {
+		w.prev = 0
+	}
+	w.curr = 0
+	w.start = now.Truncate(w.window)
+}
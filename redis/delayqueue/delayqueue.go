@@ -0,0 +1,350 @@
+package delayqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"GoTools/clock"
+)
+
+var (
+	ErrTaskExists   = errors.New("task already exists")
+	ErrTaskNotFound = errors.New("task does not exist")
+	ErrLeaseLost    = errors.New("task lease expired or claimed by another worker")
+)
+
+// 所有脚本都使用 Redis 服务端的时间，避免各实例时钟不一致
+const nowMs = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+`
+
+// KEYS: pending, tasks
+// ARGV: id, payload, delay_ms, at_ms（小于 0 时使用 now + delay_ms）
+var addScript = redis.NewScript(nowMs + `
+if redis.call('HEXISTS', KEYS[2], ARGV[1]) == 1 then
+	return 0
+end
+local due = tonumber(ARGV[4])
+if due < 0 then
+	due = now + tonumber(ARGV[3])
+end
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[1], due, ARGV[1])
+return 1
+`)
+
+// KEYS: pending, processing, tasks, attempts, leases
+// ARGV: id
+var removeScript = redis.NewScript(`
+local n = redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+redis.call('HDEL', KEYS[5], ARGV[1])
+return n
+`)
+
+// 先把超过可见性超时没有确认的任务放回 pending 重新投递，再领取到期的任务
+// 领取的任务移到 processing，分数为可见性超时的截止时间，leases 记录本次领取的 token
+// KEYS: pending, processing, tasks, attempts, leases
+// ARGV: limit, visibility_ms, token_prefix
+// 返回 {id, payload, attempts, token, ...}
+var claimScript = redis.NewScript(nowMs + `
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', now, 'LIMIT', 0, 100)
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('HDEL', KEYS[5], id)
+	redis.call('ZADD', KEYS[1], now, id)
+end
+
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'LIMIT', 0, tonumber(ARGV[1]))
+local result = {}
+for i, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	local payload = redis.call('HGET', KEYS[3], id)
+	if payload then
+		local token = ARGV[3] .. ':' .. i
+		redis.call('ZADD', KEYS[2], now + tonumber(ARGV[2]), id)
+		redis.call('HSET', KEYS[5], id, token)
+		local attempts = redis.call('HINCRBY', KEYS[4], id, 1)
+		table.insert(result, id)
+		table.insert(result, payload)
+		table.insert(result, attempts)
+		table.insert(result, token)
+	end
+end
+return result
+`)
+
+// KEYS: processing, tasks, attempts, leases
+// ARGV: id, token
+var ackScript = redis.NewScript(`
+if redis.call('HGET', KEYS[4], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+return 1
+`)
+
+// KEYS: pending, processing, leases
+// ARGV: id, token, delay_ms
+var nackScript = redis.NewScript(nowMs + `
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[3]), ARGV[1])
+return 1
+`)
+
+// KEYS: processing, leases
+// ARGV: id, token, visibility_ms
+var extendScript = redis.NewScript(nowMs + `
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZADD', KEYS[1], 'XX', now + tonumber(ARGV[3]), ARGV[1])
+return 1
+`)
+
+// Message 领取到的任务
+type Message struct {
+	ID       string
+	Payload  string
+	Attempts int64  // 第几次投递，从 1 开始
+	token    string // 本次领取的凭证，任务被重新投递后旧的凭证失效
+}
+
+// Queue 基于 Redis 有序集合的分布式延迟队列，任务重启不丢失，多个实例共享队列时每个任务只会被一个实例领取
+// 领取的任务在可见性超时内没有确认会重新投递，因此处理函数需要幂等
+type Queue struct {
+	client     *redis.Client
+	pending    string // 等待到期的任务，分数为到期时间（毫秒）
+	processing string // 已领取未确认的任务，分数为可见性超时的截止时间
+	tasks      string // 任务内容
+	attempts   string // 投递次数
+	leases     string // 当前领取的凭证
+	nonce      string
+	seq        atomic.Uint64
+	cfg        config
+}
+
+type config struct {
+	visibility   time.Duration
+	pollInterval time.Duration
+	batchSize    int
+	retryDelay   time.Duration
+	clock        clock.Clock
+}
+
+type Option func(*config)
+
+// WithVisibilityTimeout 设置领取后多久没有确认就重新投递，默认 30 秒
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.visibility = d
+	}
+}
+
+// WithPollInterval 设置 Run 没有到期任务时的轮询间隔，默认 1 秒
+func WithPollInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.pollInterval = d
+	}
+}
+
+// WithBatchSize 设置 Run 每次最多领取并同时处理的任务数，默认 10
+func WithBatchSize(n int) Option {
+	return func(c *config) {
+		c.batchSize = n
+	}
+}
+
+// WithRetryDelay 设置 Run 中处理失败的任务多久后重试，默认 5 秒
+func WithRetryDelay(d time.Duration) Option {
+	return func(c *config) {
+		c.retryDelay = d
+	}
+}
+
+// WithClock 指定 Run 轮询等待使用的时间来源，到期时间总是使用 Redis 服务端的时间
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+// New 创建名为 name 的队列，同名的队列共享任务，所有键使用同一个 hash tag，兼容 Redis Cluster
+func New(client *redis.Client, name string, opts ...Option) *Queue {
+	cfg := config{
+		visibility:   30 * time.Second,
+		pollInterval: time.Second,
+		batchSize:    10,
+		retryDelay:   5 * time.Second,
+		clock:        clock.New(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	prefix := "{" + name + "}:"
+	return &Queue{
+		client:     client,
+		pending:    prefix + "pending",
+		processing: prefix + "processing",
+		tasks:      prefix + "tasks",
+		attempts:   prefix + "attempts",
+		leases:     prefix + "leases",
+		nonce:      hex.EncodeToString(b),
+		cfg:        cfg,
+	}
+}
+
+// AddTask 添加 delay 之后到期的任务，ID 已经存在时返回 ErrTaskExists
+func (q *Queue) AddTask(ctx context.Context, id string, delay time.Duration, payload string) error {
+	return q.add(ctx, id, payload, delay.Milliseconds(), -1)
+}
+
+// AddTaskAt 添加在 at 到期的任务
+func (q *Queue) AddTaskAt(ctx context.Context, id string, at time.Time, payload string) error {
+	return q.add(ctx, id, payload, 0, max(at.UnixMilli(), 0))
+}
+
+func (q *Queue) add(ctx context.Context, id, payload string, delayMs, atMs int64) error {
+	if id == "" {
+		return errors.New("ID is empty")
+	}
+	n, err := addScript.Run(ctx, q.client, []string{q.pending, q.tasks}, id, payload, delayMs, atMs).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTaskExists
+	}
+	return nil
+}
+
+// RemoveTask 删除任务，无论是否已经被领取，例如订单支付后取消超时关单任务，任务不存在时返回 ErrTaskNotFound
+func (q *Queue) RemoveTask(ctx context.Context, id string) error {
+	n, err := removeScript.Run(ctx, q.client, q.keys(), id).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// Claim 领取最多 n 个已经到期的任务，处理完后需要调用 Ack，否则在可见性超时后重新投递
+func (q *Queue) Claim(ctx context.Context, n int) ([]*Message, error) {
+	prefix := q.nonce + ":" + strconv.FormatUint(q.seq.Add(1), 10)
+	res, err := claimScript.Run(ctx, q.client, q.keys(), n, q.cfg.visibility.Milliseconds(), prefix).Slice()
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]*Message, 0, len(res)/4)
+	for i := 0; i+3 < len(res); i += 4 {
+		msgs = append(msgs, &Message{
+			ID:       res[i].(string),
+			Payload:  res[i+1].(string),
+			Attempts: res[i+2].(int64),
+			token:    res[i+3].(string),
+		})
+	}
+	return msgs, nil
+}
+
+// Ack 确认任务处理完成并删除任务，领取已经失效时返回 ErrLeaseLost
+func (q *Queue) Ack(ctx context.Context, msg *Message) error {
+	return q.checkLease(ackScript.Run(ctx, q.client, []string{q.processing, q.tasks, q.attempts, q.leases}, msg.ID, msg.token).Int())
+}
+
+// Nack 放弃处理，任务在 delay 之后重新投递
+func (q *Queue) Nack(ctx context.Context, msg *Message, delay time.Duration) error {
+	return q.checkLease(nackScript.Run(ctx, q.client, []string{q.pending, q.processing, q.leases}, msg.ID, msg.token, delay.Milliseconds()).Int())
+}
+
+// Extend 处理时间较长时延长可见性超时，从现在开始重新计算
+func (q *Queue) Extend(ctx context.Context, msg *Message, d time.Duration) error {
+	return q.checkLease(extendScript.Run(ctx, q.client, []string{q.processing, q.leases}, msg.ID, msg.token, d.Milliseconds()).Int())
+}
+
+// Len 返回等待到期和已领取未确认的任务数
+func (q *Queue) Len(ctx context.Context) (pending, processing int64, err error) {
+	pipe := q.client.Pipeline()
+	p := pipe.ZCard(ctx, q.pending)
+	r := pipe.ZCard(ctx, q.processing)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+	return p.Val(), r.Val(), nil
+}
+
+// Run 循环领取到期的任务交给 handler 处理，同一批任务并发处理，直到 ctx 结束
+// handler 返回 nil 时确认任务，返回错误时在 WithRetryDelay 之后重试
+func (q *Queue) Run(ctx context.Context, handler func(ctx context.Context, msg *Message) error) error {
+	for {
+		msgs, err := q.Claim(ctx, q.cfg.batchSize)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil && len(msgs) > 0 {
+			q.handle(ctx, msgs, handler)
+			continue // 可能还有到期的任务，立即再领取
+		}
+		// 没有到期的任务或 Redis 暂时不可用，等待后重试
+		timer := q.cfg.clock.NewTimer(q.cfg.pollInterval)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (q *Queue) handle(ctx context.Context, msgs []*Message, handler func(ctx context.Context, msg *Message) error) {
+	var wg sync.WaitGroup
+	for _, msg := range msgs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := handler(ctx, msg); err != nil {
+				// 失败时交给可见性超时兜底，Nack 只是让重试更快
+				_ = q.Nack(context.WithoutCancel(ctx), msg, q.cfg.retryDelay)
+				return
+			}
+			_ = q.Ack(context.WithoutCancel(ctx), msg)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) keys() []string {
+	return []string{q.pending, q.processing, q.tasks, q.attempts, q.leases}
+}
+
+func (q *Queue) checkLease(n int, err error) error {
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestQueue(t *testing.T, name string, opts ...Option) *Queue {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("本地Redis不可用: %v", err)
+	}
+	q := New(client, name, opts...)
+	client.Del(context.Background(), q.keys()...)
+	t.Cleanup(func() {
+		client.Del(context.Background(), q.keys()...)
+		client.Close()
+	})
+	return q
+}
+
+func TestAddClaimAck(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, "test:delayqueue:basic")
+
+	if err := q.AddTask(ctx, "a", 0, "payload-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.AddTask(ctx, "b", time.Hour, "payload-b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.AddTask(ctx, "a", 0, "dup"); !errors.Is(err, ErrTaskExists) {
+		t.Fatalf("重复添加期望 ErrTaskExists，实际 %v", err)
+	}
+
+	msgs, err := q.Claim(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != "a" || msgs[0].Payload != "payload-a" || msgs[0].Attempts != 1 {
+		t.Fatalf("期望只领取到 a，实际 %+v", msgs)
+	}
+	// 已领取的任务不会被重复领取
+	if again, _ := q.Claim(ctx, 10); len(again) != 0 {
+		t.Fatalf("期望没有可领取的任务，实际 %d 个", len(again))
+	}
+	if err := q.Ack(ctx, msgs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Ack(ctx, msgs[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("重复确认期望 ErrLeaseLost，实际 %v", err)
+	}
+
+	pending, processing, err := q.Len(ctx)
+	if err != nil || pending != 1 || processing != 0 {
+		t.Fatalf("期望 1 个等待 0 个处理中，实际 %d %d %v", pending, processing, err)
+	}
+	if err := q.RemoveTask(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.RemoveTask(ctx, "b"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("期望 ErrTaskNotFound，实际 %v", err)
+	}
+}
+
+func TestVisibilityTimeout(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, "test:delayqueue:visibility", WithVisibilityTimeout(100*time.Millisecond))
+
+	if err := q.AddTask(ctx, "a", 0, "x"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := q.Claim(ctx, 1)
+	if err != nil || len(first) != 1 {
+		t.Fatalf("领取失败: %v %d", err, len(first))
+	}
+
+	// 没有确认，超时后重新投递
+	time.Sleep(150 * time.Millisecond)
+	second, err := q.Claim(ctx, 1)
+	if err != nil || len(second) != 1 || second[0].Attempts != 2 {
+		t.Fatalf("期望第 2 次投递，实际 %+v %v", second, err)
+	}
+	// 旧的领取已经失效
+	if err := q.Ack(ctx, first[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("期望 ErrLeaseLost，实际 %v", err)
+	}
+	if err := q.Extend(ctx, second[0], time.Second); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if msgs, _ := q.Claim(ctx, 1); len(msgs) != 0 {
+		t.Fatal("延长可见性超时后不应重新投递")
+	}
+	if err := q.Nack(ctx, second[0], 0); err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ := q.Claim(ctx, 1); len(msgs) != 1 || msgs[0].Attempts != 3 {
+		t.Fatalf("Nack 后期望立即重新投递，实际 %+v", msgs)
+	}
+}
+
+func TestRun(t *testing.T) {
+	q := newTestQueue(t, "test:delayqueue:run",
+		WithPollInterval(10*time.Millisecond), WithRetryDelay(0))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := q.AddTask(ctx, id, 50*time.Millisecond, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var done, failed atomic.Int32
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		_ = q.Run(ctx, func(ctx context.Context, msg *Message) error {
+			// b 第一次处理失败，重试后成功
+			if msg.ID == "b" && msg.Attempts == 1 {
+				failed.Add(1)
+				return errors.New("fail")
+			}
+			if done.Add(1) == 3 {
+				cancel()
+			}
+			return nil
+		})
+	}()
+	<-exited // Run 返回时这一批任务都已经确认
+	if done.Load() != 3 || failed.Load() != 1 {
+		t.Fatalf("期望成功 3 次失败 1 次，实际 %d %d", done.Load(), failed.Load())
+	}
+	pending, processing, _ := q.Len(context.Background())
+	if pending != 0 || processing != 0 {
+		t.Fatalf("期望队列为空，实际 %d %d", pending, processing)
+	}
+}
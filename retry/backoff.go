@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff 计算第 attempt 次失败后（attempt 从 1 开始）到下一次尝试的等待时间，prev 为上一次的等待时间
+type Backoff func(attempt int, prev time.Duration) time.Duration
+
+// Constant 每次等待固定的时间
+func Constant(d time.Duration) Backoff {
+	return func(int, time.Duration) time.Duration {
+		return d
+	}
+}
+
+// Exponential 等待时间从 base 开始每次翻倍，不超过 maxDelay
+func Exponential(base, maxDelay time.Duration) Backoff {
+	return func(attempt int, _ time.Duration) time.Duration {
+		return exponential(base, maxDelay, attempt)
+	}
+}
+
+// FullJitter 在 [0, 指数退避的等待时间] 内随机等待，避免大量客户端同时重试
+func FullJitter(base, maxDelay time.Duration) Backoff {
+	return func(attempt int, _ time.Duration) time.Duration {
+		return randBetween(0, exponential(base, maxDelay, attempt))
+	}
+}
+
+// DecorrelatedJitter 在 [base, 上一次等待时间的 3 倍] 内随机等待，不超过 maxDelay
+func DecorrelatedJitter(base, maxDelay time.Duration) Backoff {
+	return func(_ int, prev time.Duration) time.Duration {
+		upper := max(prev, base) * 3
+		if upper < 0 || upper > maxDelay {
+			upper = maxDelay // 防止溢出
+		}
+		return min(randBetween(base, upper), maxDelay)
+	}
+}
+
+func exponential(base, maxDelay time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt; i++ {
+		if d >= maxDelay/2 {
+			return maxDelay
+		}
+		d *= 2
+	}
+	return min(d, maxDelay)
+}
+
+// randBetween 返回 [lo, hi] 内的随机时间，hi <= lo 时返回 lo
+func randBetween(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + rand.N(hi-lo+1)
+}
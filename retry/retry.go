@@ -0,0 +1,187 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"GoTools/clock"
+	current_limiting "GoTools/current-limiting"
+)
+
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// permanentError 包装不应重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记 err 不可重试，Do 会立即返回 err 本身
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type config struct {
+	backoff     Backoff
+	maxAttempts int
+	maxElapsed  time.Duration
+	retryIf     func(err error) bool
+	budget      *current_limiting.Limiter
+	onRetry     func(attempt int, err error, delay time.Duration)
+	clock       clock.Clock
+}
+
+type Option func(*config)
+
+// WithBackoff 设置退避策略，默认 FullJitter(100ms, 10s)
+func WithBackoff(b Backoff) Option {
+	return func(c *config) {
+		c.backoff = b
+	}
+}
+
+// WithMaxAttempts 设置最多尝试的次数（包含第一次），默认 3，0 表示不限制
+func WithMaxAttempts(n int) Option {
+	return func(c *config) {
+		c.maxAttempts = n
+	}
+}
+
+// WithMaxElapsed 设置从第一次尝试开始的总时长上限，等待后会超过上限时不再重试，默认不限制
+func WithMaxElapsed(d time.Duration) Option {
+	return func(c *config) {
+		c.maxElapsed = d
+	}
+}
+
+// WithRetryIf 设置哪些错误可以重试，默认除 Permanent 和 ctx 的错误外都重试
+func WithRetryIf(fn func(err error) bool) Option {
+	return func(c *config) {
+		c.retryIf = fn
+	}
+}
+
+// WithBudget 设置重试预算，每次重试（不含第一次尝试）消耗一个令牌，令牌不足时放弃重试
+// 同一个下游的所有调用应共享一个 Limiter，下游故障时重试量不会超过令牌桶的速率
+func WithBudget(budget *current_limiting.Limiter) Option {
+	return func(c *config) {
+		c.budget = budget
+	}
+}
+
+// WithOnRetry 设置每次重试前的回调，可用于记录日志和监控
+func WithOnRetry(fn func(attempt int, err error, delay time.Duration)) Option {
+	return func(c *config) {
+		c.onRetry = fn
+	}
+}
+
+// WithClock 指定等待和计时使用的时间来源
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+// Retryer 保存一组重试配置，可以在多个调用之间复用
+type Retryer struct {
+	cfg config
+}
+
+func New(opts ...Option) *Retryer {
+	cfg := config{
+		backoff:     FullJitter(100*time.Millisecond, 10*time.Second),
+		maxAttempts: 3,
+		retryIf:     func(error) bool { return true },
+		clock:       clock.New(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Retryer{cfg: cfg}
+}
+
+// Do 使用默认配置和 opts 执行 fn 直到成功或不再重试
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	return New(opts...).Do(ctx, fn)
+}
+
+// DoValue 与 Do 相同，返回 fn 最后一次的结果
+func DoValue[T any](ctx context.Context, fn func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	var value T
+	err := Do(ctx, func(ctx context.Context) error {
+		var err error
+		value, err = fn(ctx)
+		return err
+	}, opts...)
+	return value, err
+}
+
+// Do 执行 fn 直到成功或不再重试，放弃重试时返回最后一次的错误
+// 预算不足时返回的错误同时包装 ErrBudgetExhausted，等待期间 ctx 结束时同时包装 ctx.Err()
+func (r *Retryer) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	cfg := &r.cfg
+	start := cfg.clock.Now()
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if permanent := (*permanentError)(nil); errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if !r.retryable(ctx, err) {
+			return err
+		}
+		if cfg.maxAttempts > 0 && attempt >= cfg.maxAttempts {
+			return err
+		}
+		delay = max(cfg.backoff(attempt, delay), 0)
+		if cfg.maxElapsed > 0 && cfg.clock.Since(start)+delay > cfg.maxElapsed {
+			return err
+		}
+		if cfg.budget != nil && !cfg.budget.Allow() {
+			return fmt.Errorf("%w: %w", ErrBudgetExhausted, err)
+		}
+		if cfg.onRetry != nil {
+			cfg.onRetry(attempt, err, delay)
+		}
+		if !r.sleep(ctx, delay) {
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+	}
+}
+
+func (r *Retryer) retryable(ctx context.Context, err error) bool {
+	// fn 因为 ctx 结束而失败时重试没有意义
+	if ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return false
+	}
+	return r.cfg.retryIf(err)
+}
+
+// sleep 等待 d，ctx 先结束时返回 false
+func (r *Retryer) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := r.cfg.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,165 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"GoTools/clock"
+	current_limiting "GoTools/current-limiting"
+)
+
+var errTemporary = errors.New("temporary error")
+
+func TestBackoff(t *testing.T) {
+	exp := Exponential(100*time.Millisecond, time.Second)
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if got := exp(attempt+1, 0); got != want*time.Millisecond {
+			t.Errorf("第%d次指数退避期望 %v，实际 %v", attempt+1, want*time.Millisecond, got)
+		}
+	}
+	if got := exp(1000, 0); got != time.Second {
+		t.Errorf("次数很大时不应溢出，实际 %v", got)
+	}
+	if got := Constant(time.Second)(5, 0); got != time.Second {
+		t.Errorf("固定退避期望 1s，实际 %v", got)
+	}
+
+	full := FullJitter(100*time.Millisecond, time.Second)
+	decorrelated := DecorrelatedJitter(100*time.Millisecond, time.Second)
+	var prev time.Duration
+	for i := 0; i < 1000; i++ {
+		if d := full(3, 0); d < 0 || d > 400*time.Millisecond {
+			t.Fatalf("FullJitter 应在 [0, 400ms] 内，实际 %v", d)
+		}
+		d := decorrelated(i+1, prev)
+		if d < 100*time.Millisecond || d > time.Second || d > max(prev, 100*time.Millisecond)*3 {
+			t.Fatalf("DecorrelatedJitter 超出范围，上一次 %v，本次 %v", prev, d)
+		}
+		prev = d
+	}
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	var calls int
+	var retries []int
+	err := Do(ctx, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTemporary
+		}
+		return nil
+	}, WithBackoff(Constant(0)), WithOnRetry(func(attempt int, err error, delay time.Duration) {
+		retries = append(retries, attempt)
+	}))
+	if err != nil || calls != 3 || len(retries) != 2 {
+		t.Errorf("期望第3次成功，实际 %v，调用 %d 次，重试 %v", err, calls, retries)
+	}
+
+	calls = 0
+	err = Do(ctx, func(ctx context.Context) error { calls++; return errTemporary },
+		WithBackoff(Constant(0)), WithMaxAttempts(4))
+	if !errors.Is(err, errTemporary) || calls != 4 {
+		t.Errorf("期望尝试4次后返回最后的错误，实际 %v，调用 %d 次", err, calls)
+	}
+
+	// 不可重试的错误立即返回
+	calls = 0
+	errInvalid := errors.New("invalid argument")
+	err = Do(ctx, func(ctx context.Context) error { calls++; return errInvalid },
+		WithBackoff(Constant(0)), WithRetryIf(func(err error) bool { return !errors.Is(err, errInvalid) }))
+	if err != errInvalid || calls != 1 {
+		t.Errorf("不可重试的错误应立即返回，实际 %v，调用 %d 次", err, calls)
+	}
+	calls = 0
+	err = Do(ctx, func(ctx context.Context) error { calls++; return Permanent(errTemporary) }, WithBackoff(Constant(0)))
+	if err != errTemporary || calls != 1 {
+		t.Errorf("Permanent 应立即返回原始错误，实际 %v，调用 %d 次", err, calls)
+	}
+
+	n, err := DoValue(ctx, func(ctx context.Context) (int, error) { return 42, nil })
+	if n != 42 || err != nil {
+		t.Errorf("DoValue 期望 42，实际 %d %v", n, err)
+	}
+}
+
+func TestDoMaxElapsed(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	var calls int
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		fc.Advance(400 * time.Millisecond) // 模拟每次调用耗时
+		return errTemporary
+	}, WithClock(fc), WithBackoff(Constant(0)), WithMaxAttempts(0), WithMaxElapsed(time.Second))
+	// 400ms、800ms 后继续重试，1200ms 超过上限
+	if !errors.Is(err, errTemporary) || calls != 3 {
+		t.Errorf("期望调用3次后放弃，实际 %v，调用 %d 次", err, calls)
+	}
+}
+
+func TestDoWaitAndCancel(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	r := New(WithClock(fc), WithBackoff(Constant(time.Second)))
+	calls := make(chan struct{}, 3)
+	result := make(chan error, 1)
+	go func() {
+		result <- r.Do(context.Background(), func(ctx context.Context) error {
+			calls <- struct{}{}
+			return errTemporary
+		})
+	}()
+	<-calls
+	fc.BlockUntil(1)
+	if len(calls) != 0 {
+		t.Fatalf("等待结束前不应重试")
+	}
+	fc.Advance(time.Second)
+	<-calls
+	fc.BlockUntil(1)
+	fc.Advance(time.Second)
+	if err := <-result; !errors.Is(err, errTemporary) || len(calls) != 1 {
+		t.Errorf("期望默认尝试3次，实际 %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		result <- r.Do(ctx, func(ctx context.Context) error { return errTemporary })
+	}()
+	fc.BlockUntil(1)
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) || !errors.Is(err, errTemporary) {
+		t.Errorf("等待期间取消应同时返回 ctx 的错误和最后的错误，实际 %v", err)
+	}
+	if err := r.Do(ctx, func(ctx context.Context) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("ctx 已取消时不应执行，实际 %v", err)
+	}
+}
+
+func TestDoBudget(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	budget := current_limiting.NewLimiter(1, 2, current_limiting.WithClock(fc), current_limiting.WithInitialTokens(2))
+	r := New(WithBudget(budget), WithBackoff(Constant(0)), WithMaxAttempts(10))
+
+	var calls int
+	fail := func(ctx context.Context) error { calls++; return errTemporary }
+	if err := r.Do(context.Background(), fail); !errors.Is(err, ErrBudgetExhausted) || !errors.Is(err, errTemporary) {
+		t.Errorf("预算耗尽时期望 ErrBudgetExhausted，实际 %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("预算为2时期望调用3次，实际 %d", calls)
+	}
+	// 预算被所有调用共享，没有恢复前不再重试
+	calls = 0
+	_ = r.Do(context.Background(), fail)
+	if calls != 1 {
+		t.Errorf("预算耗尽后只应调用1次，实际 %d", calls)
+	}
+	fc.Advance(time.Second)
+	calls = 0
+	_ = r.Do(context.Background(), fail)
+	if calls != 2 {
+		t.Errorf("预算恢复1个令牌后期望调用2次，实际 %d", calls)
+	}
+}
@@ -0,0 +1,67 @@
+package timewheel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 2, 30, 0, time.UTC) // 周一
+	tests := []struct {
+		expr string
+		want []time.Time
+	}{
+		{"*/5 * * * *", []time.Time{
+			time.Date(2024, 1, 15, 10, 5, 0, 0, time.UTC),
+			time.Date(2024, 1, 15, 10, 10, 0, 0, time.UTC),
+		}},
+		{"*/20 * * * * *", []time.Time{
+			time.Date(2024, 1, 15, 10, 2, 40, 0, time.UTC),
+			time.Date(2024, 1, 15, 10, 3, 0, 0, time.UTC),
+		}},
+		{"30 9 * * MON-FRI", []time.Time{
+			time.Date(2024, 1, 16, 9, 30, 0, 0, time.UTC),
+			time.Date(2024, 1, 17, 9, 30, 0, 0, time.UTC),
+		}},
+		{"0 0 1,15 * *", []time.Time{
+			time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+		}},
+		// 日和星期都有限制时满足任意一个即可
+		{"0 12 20 * 0", []time.Time{
+			time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 21, 12, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 28, 12, 0, 0, 0, time.UTC),
+		}},
+		{"0 0 29 2 *", []time.Time{
+			time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		}},
+		{"15 10 * dec 7", []time.Time{
+			time.Date(2024, 12, 1, 10, 15, 0, 0, time.UTC),
+		}},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("%s 解析失败: %v", tt.expr, err)
+		}
+		next := base
+		for _, want := range tt.want {
+			if next = s.Next(next); !next.Equal(want) {
+				t.Errorf("%s 期望 %v，实际 %v", tt.expr, want, next)
+				break
+			}
+		}
+	}
+
+	s, _ := ParseCron("0 0 30 2 *")
+	if next := s.Next(base); !next.IsZero() {
+		t.Errorf("2 月 30 日不存在，期望零值，实际 %v", next)
+	}
+	for _, expr := range []string{"* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("%q 应解析失败", expr)
+		}
+	}
+}
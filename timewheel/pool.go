@@ -0,0 +1,76 @@
+package timewheel
+
+import (
+	"container/list"
+	"sync"
+)
+
+// workerPool 执行任务的协程池，size 为 0 时每次执行启动一个协程
+type workerPool struct {
+	size   int
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  *list.List // 等待执行的函数，元素为 func()
+	closed bool
+	wg     sync.WaitGroup // 正在执行和等待执行的函数
+}
+
+func newWorkerPool(size int) *workerPool {
+	p := &workerPool{size: size, queue: list.New()}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < size; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+// submit 提交一个函数，协程池已经关闭时返回 false
+func (p *workerPool) submit(fn func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.wg.Add(1)
+	if p.size == 0 {
+		go func() {
+			defer p.wg.Done()
+			fn()
+		}()
+		return true
+	}
+	p.queue.PushBack(fn)
+	p.cond.Signal()
+	return true
+}
+
+func (p *workerPool) worker() {
+	for {
+		p.mu.Lock()
+		for p.queue.Len() == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if p.queue.Len() == 0 {
+			p.mu.Unlock()
+			return // 已经关闭并且没有剩余的函数
+		}
+		fn := p.queue.Remove(p.queue.Front()).(func())
+		p.mu.Unlock()
+
+		fn()
+		p.wg.Done()
+	}
+}
+
+// close 不再接受新的函数，已经提交的函数仍会执行完
+func (p *workerPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.cond.Broadcast()
+}
+
+// wait 等待所有已经提交的函数执行完
+func (p *workerPool) wait() {
+	p.wg.Wait()
+}
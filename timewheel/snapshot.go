@@ -0,0 +1,197 @@
+package timewheel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// CatchUpPolicy 恢复快照时已经过了执行时间的任务的处理方式
+type CatchUpPolicy int
+
+const (
+	CatchUpOnce CatchUpPolicy = iota // 立即补执行一次，之后按原来的规则继续，默认
+	CatchUpSkip                      // 放弃错过的执行，重复任务从当前时间开始计算下一次，只执行一次的任务直接丢弃
+	CatchUpAll                       // 依次补上错过的每一次执行，最多 maxCatchUp 次
+)
+
+// maxCatchUp CatchUpAll 策略下最多补执行的次数，避免停机很久后间隔很短的任务补执行过多
+const maxCatchUp = 1000
+
+// WithCatchUp 设置恢复快照时已经过了执行时间的任务的处理方式，默认 CatchUpOnce
+func WithCatchUp(policy CatchUpPolicy) Option {
+	return func(t *TimeWheel) {
+		t.catchUp = policy
+	}
+}
+
+// TaskSnapshot 快照中的一个任务
+type TaskSnapshot struct {
+	ID       string        `json:"id"`
+	Job      string        `json:"job"`                // 注册的任务名
+	Next     time.Time     `json:"next"`               // 下一次执行的时间
+	Times    int64         `json:"times"`              // 剩余执行次数，-1 表示一直执行
+	Interval time.Duration `json:"interval,omitempty"` // 重复执行的间隔，与 Cron 都为空时只执行一次
+	Cron     string        `json:"cron,omitempty"`
+	Overrun  OverrunPolicy `json:"overrun,omitempty"`
+}
+
+// RegisterJob 注册名为 name 的任务，使用 WithJobName 添加的任务和从快照恢复的任务按名字找到要执行的函数
+func (t *TimeWheel) RegisterJob(name string, job ContextJob) error {
+	if name == "" {
+		return errors.New("job name is empty")
+	}
+	if job == nil {
+		return errors.New("job is nil")
+	}
+	if _, loaded := t.jobs.LoadOrStore(name, job); loaded {
+		return fmt.Errorf("job %q already registered", name)
+	}
+	return nil
+}
+
+// Snapshot 返回所有设置了任务名的未结束任务，按 ID 排序，
+// 使用 AddSchedule 添加的自定义 Schedule 无法序列化，不会写入快照
+func (t *TimeWheel) Snapshot() ([]TaskSnapshot, error) {
+	ch := make(chan []TaskSnapshot, 1)
+	select {
+	case t.snapshotCh <- ch:
+		return <-ch, nil
+	case <-t.ctx.Done():
+		return nil, ErrStopped
+	}
+}
+
+// SaveSnapshot 把快照写入文件，先写临时文件再重命名，写入过程中退出不会破坏已有的快照，需要在 Stop 之前调用
+func (t *TimeWheel) SaveSnapshot(path string) error {
+	snapshots, err := t.Snapshot()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadSnapshot 从文件恢复任务，文件不存在时不做任何事，第一次启动时可以直接调用
+func (t *TimeWheel) LoadSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snapshots []TaskSnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return t.Restore(snapshots)
+}
+
+// Restore 恢复快照中的任务，已经过了执行时间的任务按 WithCatchUp 处理，
+// 任务名没有注册或 ID 已经存在的任务不会恢复，其余任务照常恢复，返回所有失败任务的错误
+func (t *TimeWheel) Restore(snapshots []TaskSnapshot) error {
+	var errs []error
+	for _, s := range snapshots {
+		if err := t.restore(s); err != nil {
+			errs = append(errs, fmt.Errorf("restore task %q: %w", s.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *TimeWheel) restore(s TaskSnapshot) error {
+	if s.Times == 0 {
+		return nil // 已经没有剩余的执行次数
+	}
+	if s.Cron == "" && s.Interval <= 0 && s.Times != 1 {
+		// 快照文件被手动修改或损坏，间隔为 0 的重复任务无法恢复
+		return errors.New("repeating task must have a positive interval or a cron expression")
+	}
+	var schedule Schedule = every(s.Interval)
+	if s.Cron != "" {
+		var err error
+		if schedule, err = ParseCron(s.Cron); err != nil {
+			return err
+		}
+	}
+	task := newTask(s.ID, nil, schedule, s.Next, []TaskOption{WithJobName(s.Job), WithTimes(s.Times), WithOverrun(s.Overrun)})
+
+	now := t.clock.Now()
+	if s.Next.After(now) {
+		_, err := t.add(task)
+		return err
+	}
+	switch t.catchUp {
+	case CatchUpSkip:
+		if task.times == 1 {
+			return nil // 只执行一次的任务，错过即丢弃
+		}
+		next := schedule.Next(now)
+		if !next.After(now) {
+			return nil
+		}
+		task.next = next
+	case CatchUpAll:
+		// 统计错过的次数，第一次在时间轮中到期执行，其余的排队依次执行
+		limit := int64(maxCatchUp)
+		if s.Times > 0 {
+			limit = min(limit, s.Times)
+		}
+		var missed int64
+		for at := s.Next; !at.IsZero() && !at.After(now) && missed < limit; at = schedule.Next(at) {
+			missed++
+		}
+		if task.times > 0 {
+			task.times -= missed - 1
+		}
+		task.pending = int(missed - 1)
+		task.next = now
+	default:
+		task.next = now
+	}
+	_, err := t.add(task)
+	return err
+}
+
+// snapshot 收集快照，调用方为时间轮协程，此时可以安全读取 times
+func (t *TimeWheel) snapshot() []TaskSnapshot {
+	snapshots := []TaskSnapshot{}
+	t.tasks.Range(func(_, val any) bool {
+		task := val.(*Task)
+		if task.jobName == "" || task.times == 0 {
+			return true
+		}
+		s := TaskSnapshot{ID: task.ID, Job: task.jobName, Times: task.times, Overrun: task.overrun}
+		switch schedule := task.schedule.(type) {
+		case every:
+			s.Interval = time.Duration(schedule)
+		case *cronSchedule:
+			s.Cron = schedule.expr
+		default:
+			return true
+		}
+		task.mu.Lock()
+		s.Next = task.next
+		cancelled := task.cancelled
+		task.mu.Unlock()
+		if !cancelled && !s.Next.IsZero() {
+			snapshots = append(snapshots, s)
+		}
+		return true
+	})
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return snapshots
+}
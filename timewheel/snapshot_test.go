@@ -0,0 +1,141 @@
+package timewheel
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"GoTools/clock"
+)
+
+// collect 收集任务执行的次数，直到一段时间内没有新的执行
+func collect(done <-chan string) map[string]int {
+	counts := map[string]int{}
+	for {
+		select {
+		case key := <-done:
+			counts[key]++
+		case <-time.After(50 * time.Millisecond):
+			return counts
+		}
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	done := make(chan string, 16)
+	job := func(ctx context.Context, key string) error {
+		done <- key
+		return nil
+	}
+	tw, fc := newFakeTimeWheel(t, time.Second, 60)
+	if err := tw.RegisterJob("job", job); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.RegisterJob("job", job); err == nil {
+		t.Error("重复注册应返回错误")
+	}
+	if _, err := tw.Schedule("missing", time.Second, nil, WithJobName("missing")); err == nil {
+		t.Error("未注册的任务名应返回错误")
+	}
+	tw.Schedule("repeat", 10*time.Second, nil, WithJobName("job"), WithTimes(5))
+	tw.AddAt("once", time.Unix(30, 0), nil, WithJobName("job"))
+	tw.AddCron("cron", "0 * * * * *", nil, WithJobName("job"))
+	tw.AddTask("plain", time.Second, func(key string) {}, -1) // 没有任务名，不写入快照
+
+	tick(fc, time.Second, 10)
+	waitExec(t, done)
+	// 带间隔的一次性任务，错过后不能按间隔重新计算执行时间
+	tw.Schedule("delayed", 20*time.Second, nil, WithJobName("job"), WithTimes(1))
+
+	path := filepath.Join(t.TempDir(), "timewheel.json")
+	if err := tw.SaveSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	snapshots, _ := tw.Snapshot()
+	tw.Stop()
+	want := []TaskSnapshot{
+		{ID: "cron", Job: "job", Next: time.Unix(60, 0), Times: -1, Cron: "0 * * * * *"},
+		{ID: "delayed", Job: "job", Next: time.Unix(30, 0), Times: 1, Interval: 20 * time.Second},
+		{ID: "once", Job: "job", Next: time.Unix(30, 0), Times: 1},
+		{ID: "repeat", Job: "job", Next: time.Unix(20, 0), Times: 4, Interval: 10 * time.Second},
+	}
+	if len(snapshots) != len(want) {
+		t.Fatalf("期望 %d 个任务，实际 %+v", len(want), snapshots)
+	}
+	for i := range want {
+		if s := snapshots[i]; s.ID != want[i].ID || !s.Next.Equal(want[i].Next) || s.Times != want[i].Times ||
+			s.Interval != want[i].Interval || s.Cron != want[i].Cron {
+			t.Errorf("期望 %+v，实际 %+v", want[i], s)
+		}
+	}
+
+	// 在第 45 秒恢复，repeat 错过了 20、30、40 秒三次，once 和 delayed 也已经过期，cron 还没到期
+	tests := []struct {
+		policy     CatchUpPolicy
+		runs       map[string]int
+		repeatLeft int64
+	}{
+		{CatchUpOnce, map[string]int{"repeat": 1, "once": 1, "delayed": 1}, 3},
+		{CatchUpSkip, map[string]int{}, 4},
+		{CatchUpAll, map[string]int{"repeat": 3, "once": 1, "delayed": 1}, 1},
+	}
+	for _, tt := range tests {
+		fc := clock.NewFakeClock(time.Unix(45, 0))
+		tw, _ := NewTimeWheel(time.Second, 60, WithClock(fc), WithCatchUp(tt.policy))
+		if err := tw.LoadSnapshot(path); err == nil {
+			t.Error("任务名没有注册时恢复应返回错误")
+		}
+		tw.RegisterJob("job", job)
+		if err := tw.LoadSnapshot(path); err != nil {
+			t.Fatal(err)
+		}
+		runs := collect(done)
+		if len(runs) != len(tt.runs) || runs["repeat"] != tt.runs["repeat"] || runs["once"] != tt.runs["once"] ||
+			runs["delayed"] != tt.runs["delayed"] {
+			t.Errorf("策略 %d 期望执行 %v，实际 %v", tt.policy, tt.runs, runs)
+		}
+		repeat, ok := tw.Task("repeat")
+		if !ok || !repeat.NextRun().Equal(time.Unix(55, 0)) {
+			t.Errorf("策略 %d 期望 repeat 在第 55 秒执行", tt.policy)
+		}
+		for _, id := range []string{"once", "delayed"} {
+			if _, ok := tw.Task(id); ok {
+				t.Errorf("策略 %d 只执行一次的任务 %s 恢复后不应继续存在", tt.policy, id)
+			}
+		}
+		snapshots, _ := tw.Snapshot()
+		for _, s := range snapshots {
+			if s.ID == "repeat" && s.Times != tt.repeatLeft {
+				t.Errorf("策略 %d 期望 repeat 剩余 %d 次，实际 %d", tt.policy, tt.repeatLeft, s.Times)
+			}
+		}
+		tw.Stop()
+	}
+
+	tw, _ = NewTimeWheel(time.Second, 60)
+	defer tw.Stop()
+	if err := tw.LoadSnapshot(filepath.Join(t.TempDir(), "none.json")); err != nil {
+		t.Errorf("快照文件不存在时不应返回错误: %v", err)
+	}
+}
+
+// 测试间隔为 0 的重复任务不会被恢复
+func TestRestoreZeroInterval(t *testing.T) {
+	tw, _ := newFakeTimeWheel(t, time.Second, 60)
+	defer tw.Stop()
+	tw.RegisterJob("job", func(ctx context.Context, key string) error { return nil })
+
+	for _, times := range []int64{5, -1} {
+		err := tw.Restore([]TaskSnapshot{{ID: "bad", Job: "job", Next: time.Unix(0, 0), Times: times}})
+		if err == nil {
+			t.Errorf("间隔为 0 执行 %d 次的任务恢复应返回错误", times)
+		}
+		if _, ok := tw.Task("bad"); ok {
+			t.Error("恢复失败的任务不应加入时间轮")
+		}
+	}
+	if err := tw.Restore([]TaskSnapshot{{ID: "once", Job: "job", Next: time.Unix(0, 0), Times: 1}}); err != nil {
+		t.Errorf("只执行一次的任务应能恢复: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package timewheel
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ContextJob 可以感知取消的任务，ctx 在任务被取消或时间轮停止时结束，返回的错误可以通过 TaskHandle.Err 获取
+type ContextJob func(ctx context.Context, key string) error
+
+// OverrunPolicy 重复任务到期时上一次执行还没有结束的处理方式
+type OverrunPolicy int
+
+const (
+	OverrunConcurrent OverrunPolicy = iota // 同时执行，默认
+	OverrunSkip                            // 跳过本次执行
+	OverrunQueue                           // 等上一次结束后再执行，错过的次数都会补上
+)
+
+// PanicError 任务 panic 时记录的错误
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("timewheel: job panicked: %v", e.Value)
+}
+
+type Task struct {
+	ID         string
+	schedule   Schedule
+	expiration int64 // 到期的 tick
+	bucket     *bucket
+	elem       *list.Element
+	job        ContextJob
+	jobName    string // 注册的任务名，设置后任务会写入快照
+	times      int64  //执行多少次 -1 一直执行
+	overrun    OverrunPolicy
+	runs       atomic.Int64
+
+	mu        sync.Mutex
+	next      time.Time                    // 下一次执行的时间，没有下一次时为零值，由时间轮协程修改
+	err       error                        // 最近一次执行返回的错误
+	cancelled bool                         // 是否已经调用过 Cancel
+	running   map[int64]context.CancelFunc // 正在执行的任务的 cancel
+	runID     int64
+	active    int // 已经提交还没有结束的执行次数
+	pending   int // OverrunQueue 策略下排队的执行次数
+}
+
+type TaskOption func(*Task)
+
+// WithOverrun 设置上一次执行还没有结束时的处理方式，默认 OverrunConcurrent
+func WithOverrun(policy OverrunPolicy) TaskOption {
+	return func(t *Task) {
+		t.overrun = policy
+	}
+}
+
+// WithJobName 使用 RegisterJob 注册的名为 name 的任务，此时 job 参数可以为 nil，
+// 只有设置了任务名的任务才会写入快照，恢复时按任务名找到要执行的函数
+func WithJobName(name string) TaskOption {
+	return func(t *Task) {
+		t.jobName = name
+	}
+}
+
+// WithTimes 设置执行次数，默认 1 次，-1 表示一直执行
+func WithTimes(times int64) TaskOption {
+	return func(t *Task) {
+		t.times = times
+	}
+}
+
+func newTask(id string, job ContextJob, schedule Schedule, next time.Time, opts []TaskOption) *Task {
+	task := &Task{
+		ID:       id,
+		schedule: schedule,
+		job:      job,
+		times:    1,
+		next:     next,
+		running:  make(map[int64]context.CancelFunc),
+	}
+	for _, opt := range opts {
+		opt(task)
+	}
+	return task
+}
+
+// TaskHandle 已加入时间轮的任务
+type TaskHandle struct {
+	tw   *TimeWheel
+	task *Task
+}
+
+func (h *TaskHandle) ID() string {
+	return h.task.ID
+}
+
+// Cancel 取消任务的后续执行，并取消正在执行的任务的 ctx，任务已经结束或取消过时返回 false
+func (h *TaskHandle) Cancel() bool {
+	task := h.task
+	task.mu.Lock()
+	task.cancelled = true
+	for _, cancel := range task.running {
+		cancel()
+	}
+	task.mu.Unlock()
+
+	if !h.tw.tasks.CompareAndDelete(task.ID, task) {
+		return false
+	}
+	select {
+	case h.tw.removeTaskCh <- task:
+	case <-h.tw.ctx.Done():
+	}
+	return true
+}
+
+// Reset 把任务的下一次执行时间改为 delay 之后，之后按原来的规则继续执行，任务已经结束或取消时返回 false
+func (h *TaskHandle) Reset(delay time.Duration) bool {
+	if val, ok := h.tw.tasks.Load(h.task.ID); !ok || val != h.task {
+		return false
+	}
+	select {
+	case h.tw.resetTaskCh <- resetRequest{task: h.task, next: h.tw.clock.Now().Add(delay)}:
+		return true
+	case <-h.tw.ctx.Done():
+		return false
+	}
+}
+
+// NextRun 返回下一次执行的时间，任务已经结束或取消时返回零值
+func (h *TaskHandle) NextRun() time.Time {
+	h.task.mu.Lock()
+	defer h.task.mu.Unlock()
+	if h.task.cancelled {
+		return time.Time{}
+	}
+	return h.task.next
+}
+
+// Runs 返回任务已经开始执行的次数
+func (h *TaskHandle) Runs() int64 {
+	return h.task.runs.Load()
+}
+
+// Err 返回最近一次执行结束时返回的错误
+func (h *TaskHandle) Err() error {
+	h.task.mu.Lock()
+	defer h.task.mu.Unlock()
+	return h.task.err
+}
+
+type resetRequest struct {
+	task *Task
+	next time.Time
+}
+
+// run 执行一次任务，ctx 在任务被取消、时间轮停止或执行结束时结束，panic 会被转换为 *PanicError
+func (task *Task) run(parent context.Context) (err error) {
+	if parent.Err() != nil {
+		return nil // 时间轮已经停止，排队中的执行直接放弃
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	task.mu.Lock()
+	if task.cancelled {
+		task.mu.Unlock()
+		return nil
+	}
+	task.runID++
+	id := task.runID
+	task.running[id] = cancel
+	task.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		task.mu.Lock()
+		delete(task.running, id)
+		task.err = err
+		task.mu.Unlock()
+	}()
+	return task.job(ctx, task.ID)
+}
+
+// setNext 设置下一次执行时间，调用方为时间轮协程
+func (task *Task) setNext(next time.Time) {
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	task.next = next
+}
@@ -1,46 +1,90 @@
 package timewheel
 
 import (
+	"container/heap"
 	"container/list"
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
+
+	"GoTools/clock"
 )
 
 type Job func(key string)
 
+var (
+	ErrStopped     = errors.New("time wheel is stopped")
+	ErrStopTimeout = errors.New("timed out waiting for running jobs to finish")
+)
+
+// TimeWheel 分层时间轮，最底层每个槽为 interval，每层 slotsNum 个槽，
+// 超出底层范围的任务放到按需创建的上层时间轮中，随着时间推进逐层降级，直到在底层到期执行
 type TimeWheel struct {
 	interval     time.Duration
-	slots        []*list.List
 	slotsNum     int64
-	currentSlots int64
-	ticker       *time.Ticker
+	startTime    time.Time // 第 0 个 tick 的时间
+	wheel        *wheel    // 最底层时间轮
+	queue        bucketQueue
+	clock        clock.Clock
 	mt           sync.Mutex
 	isRun        bool
 	tasks        sync.Map
 	addTaskCh    chan *Task
-	removeTaskCh chan string
+	removeTaskCh chan *Task
+	resetTaskCh  chan resetRequest
+	snapshotCh   chan chan []TaskSnapshot
 	closeCh      chan struct{}
+	ctx          context.Context // 停止时取消，所有任务的 ctx 都派生自它
+	cancel       context.CancelFunc
+	pool         *workerPool
+	workers      int
+	onError      func(id string, err error)
+	stopTimeout  time.Duration
+	jobs         sync.Map // 任务名 -> ContextJob
+	catchUp      CatchUpPolicy
 }
 
-type Task struct {
-	ID         string
-	createTime time.Time
-	delay      time.Duration
-	slots      int64
-	circle     int64 // 多少圈
-	job        Job
-	times      int64 //执行多少次 -1 一直执行
+type Option func(*TimeWheel)
+
+// WithClock 指定时间来源，测试中可传入 clock.FakeClock 手动推进时间
+func WithClock(c clock.Clock) Option {
+	return func(t *TimeWheel) {
+		t.clock = c
+	}
+}
+
+// WithWorkers 用 n 个协程执行任务，同时到期的任务超过 n 个时排队执行，默认每次执行启动一个协程
+func WithWorkers(n int) Option {
+	return func(t *TimeWheel) {
+		t.workers = n
+	}
+}
+
+// WithErrorHandler 设置任务返回错误或 panic 时的回调，panic 时 err 为 *PanicError
+func WithErrorHandler(fn func(id string, err error)) Option {
+	return func(t *TimeWheel) {
+		t.onError = fn
+	}
 }
 
+// WithStopTimeout 设置 Stop 等待正在执行的任务结束的最长时间，默认 10 秒，0 表示一直等待
+func WithStopTimeout(d time.Duration) Option {
+	return func(t *TimeWheel) {
+		t.stopTimeout = d
+	}
+}
+
+// DefaultTimeWheel 毫秒精度的时间轮，每层 1000 个槽，各层一圈分别为 1 秒、16 分钟、11 天……
 func DefaultTimeWheel() *TimeWheel {
-	tw, _ := NewTimeWheel(time.Second, 60*60*24)
+	tw, _ := NewTimeWheel(time.Millisecond, 1000)
 	return tw
 }
 
-func NewTimeWheel(interval time.Duration, slotsNum int64) (*TimeWheel, error) {
-	if interval < time.Second {
-		return nil, errors.New("minimum interval is 1 second")
+func NewTimeWheel(interval time.Duration, slotsNum int64, opts ...Option) (*TimeWheel, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval must be greater than 0")
 	}
 	if slotsNum <= 0 {
 		return nil, errors.New("slots num must be greater than 0")
@@ -48,10 +92,16 @@ func NewTimeWheel(interval time.Duration, slotsNum int64) (*TimeWheel, error) {
 	tw := &TimeWheel{
 		interval:     interval,
 		slotsNum:     slotsNum,
-		slots:        make([]*list.List, slotsNum),
 		addTaskCh:    make(chan *Task),
-		removeTaskCh: make(chan string),
+		removeTaskCh: make(chan *Task),
+		resetTaskCh:  make(chan resetRequest),
+		snapshotCh:   make(chan chan []TaskSnapshot),
 		closeCh:      make(chan struct{}),
+		clock:        clock.New(),
+		stopTimeout:  10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(tw)
 	}
 	tw.start()
 	return tw, nil
@@ -59,131 +109,310 @@ func NewTimeWheel(interval time.Duration, slotsNum int64) (*TimeWheel, error) {
 
 func (t *TimeWheel) start() {
 	if !t.isRun {
-		t.slots = make([]*list.List, t.slotsNum)
-		for i := int64(0); i < t.slotsNum; i++ {
-			t.slots[i] = list.New()
-		}
-		t.ticker = time.NewTicker(t.interval)
+		t.startTime = t.clock.Now()
+		t.wheel = newWheel(1, t.slotsNum, 0)
+		t.queue = nil
+		t.ctx, t.cancel = context.WithCancel(context.Background())
+		t.pool = newWorkerPool(max(t.workers, 0))
 		t.mt.Lock()
 		t.isRun = true
 		go t.run()
 		t.mt.Unlock()
 	}
 }
-func (t *TimeWheel) Stop() {
-	if t.isRun {
-		t.mt.Lock()
-		t.isRun = false
-		t.mt.Unlock()
-		t.closeCh <- struct{}{}
+
+// Stop 停止时间轮并取消正在执行的任务的 ctx，等待它们结束，超过 WithStopTimeout 时返回 ErrStopTimeout
+func (t *TimeWheel) Stop() error {
+	if !t.isRun {
+		return nil
+	}
+	t.mt.Lock()
+	t.isRun = false
+	t.mt.Unlock()
+	t.cancel()
+	t.closeCh <- struct{}{}
+	t.pool.close()
+
+	done := make(chan struct{})
+	go func() {
+		t.pool.wait()
+		close(done)
+	}()
+	if t.stopTimeout <= 0 {
+		<-done
+		return nil
+	}
+	timer := t.clock.NewTimer(t.stopTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C():
+		return ErrStopTimeout
 	}
 }
 
+// AddTask 添加延迟 delay 执行的任务，times 为执行次数，默认 1 次，-1 表示一直执行，每次间隔 delay
 func (t *TimeWheel) AddTask(ID string, delay time.Duration, job Job, times ...int64) error {
-	if ID == "" {
-		return errors.New("ID is empty")
+	var opts []TaskOption
+	if len(times) > 0 {
+		opts = append(opts, WithTimes(times[0]))
 	}
+	_, err := t.Schedule(ID, delay, func(ctx context.Context, key string) error {
+		job(key)
+		return nil
+	}, opts...)
+	return err
+}
+
+// Schedule 添加延迟 delay 执行的任务，重复执行时每次间隔 delay
+func (t *TimeWheel) Schedule(ID string, delay time.Duration, job ContextJob, opts ...TaskOption) (*TaskHandle, error) {
 	if delay < t.interval {
-		return errors.New("the delay time must be greater than the interval time")
+		return nil, errors.New("the delay time must be greater than the interval time")
 	}
-	var timesInt64 int64 = 1
-	if len(times) > 0 {
-		timesInt64 = times[0]
+	return t.add(newTask(ID, job, every(delay), t.clock.Now().Add(delay), opts))
+}
+
+// AddAt 添加在 at 执行一次的任务，at 已经过去时在下一个 tick 执行，不能用 WithTimes 修改执行次数
+func (t *TimeWheel) AddAt(ID string, at time.Time, job ContextJob, opts ...TaskOption) (*TaskHandle, error) {
+	task := newTask(ID, job, every(0), at, opts)
+	if task.times != 1 {
+		return nil, errors.New("AddAt task can only run once")
 	}
-	_, ok := t.tasks.Load(ID)
-	if ok {
-		return errors.New("ID already exists")
+	return t.add(task)
+}
+
+// AddCron 添加按 cron 表达式一直执行的任务，表达式格式见 ParseCron
+func (t *TimeWheel) AddCron(ID string, expr string, job ContextJob, opts ...TaskOption) (*TaskHandle, error) {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
 	}
-	task := &Task{
-		ID:         ID,
-		createTime: time.Now(),
-		job:        job,
-		delay:      delay,
-		times:      timesInt64,
+	return t.AddSchedule(ID, schedule, job, opts...)
+}
+
+// AddSchedule 添加按 schedule 一直执行的任务
+func (t *TimeWheel) AddSchedule(ID string, schedule Schedule, job ContextJob, opts ...TaskOption) (*TaskHandle, error) {
+	next := schedule.Next(t.clock.Now())
+	if next.IsZero() {
+		return nil, errors.New("schedule has no next run time")
+	}
+	return t.add(newTask(ID, job, schedule, next, append([]TaskOption{WithTimes(-1)}, opts...)))
+}
+
+func (t *TimeWheel) add(task *Task) (*TaskHandle, error) {
+	if task.ID == "" {
+		return nil, errors.New("ID is empty")
+	}
+	if task.jobName != "" {
+		job, ok := t.jobs.Load(task.jobName)
+		if !ok {
+			return nil, fmt.Errorf("job %q is not registered", task.jobName)
+		}
+		task.job = job.(ContextJob)
+	}
+	if task.job == nil {
+		return nil, errors.New("job is nil")
+	}
+	// 在调用方协程中登记任务，保证返回后立即可见
+	if _, loaded := t.tasks.LoadOrStore(task.ID, task); loaded {
+		return nil, errors.New("ID already exists")
+	}
+	select {
+	case t.addTaskCh <- task:
+		return &TaskHandle{tw: t, task: task}, nil
+	case <-t.ctx.Done():
+		t.tasks.CompareAndDelete(task.ID, task)
+		return nil, ErrStopped
 	}
-	t.addTaskCh <- task
-	return nil
 }
 
+// RemoveTask 删除任务，正在执行的任务的 ctx 会被取消
 func (t *TimeWheel) RemoveTask(ID string) error {
-	_, ok := t.tasks.Load(ID)
-	if !ok {
+	val, ok := t.tasks.Load(ID)
+	if !ok || !(&TaskHandle{tw: t, task: val.(*Task)}).Cancel() {
 		return errors.New("ID does not exist")
 	}
-	t.removeTaskCh <- ID
 	return nil
 }
 
-func (t *TimeWheel) addTask(task *Task, first bool) {
-	task.circle, task.slots = t.getCircleAndSlots(task.delay, first)
-	ele := t.slots[task.slots].PushBack(task)
-	t.tasks.Store(task.ID, ele)
+// Task 返回 ID 对应的任务
+func (t *TimeWheel) Task(ID string) (*TaskHandle, bool) {
+	val, ok := t.tasks.Load(ID)
+	if !ok {
+		return nil, false
+	}
+	return &TaskHandle{tw: t, task: val.(*Task)}, true
+}
+
+// addTask 按任务的下一次执行时间放入时间轮，调用方为时间轮协程
+func (t *TimeWheel) addTask(task *Task) {
+	// 先处理已经到期的槽，再把时间轮推进到当前时间，避免新任务按过时的当前时间放入过高的层
+	// 两步使用同一个当前时间，否则两次读时钟之间到期的槽会留在队列中被时间轮越过
+	now := t.currentTick()
+	t.expire(now)
+	t.wheel.advanceClock(now)
+	task.expiration = t.ticksOf(task.next)
+	t.schedule(task)
+}
+
+// schedule 把任务放入时间轮，已经到期的任务立即执行并计算下一次执行时间
+func (t *TimeWheel) schedule(task *Task) {
+	for !t.wheel.add(task, &t.queue) {
+		if task.times > 0 {
+			task.times--
+		}
+		var next time.Time
+		if task.times != 0 {
+			next = task.schedule.Next(task.next)
+			if !next.IsZero() && t.ticksOf(next) <= t.wheel.currentTime {
+				// 错过的执行不再补，从当前时间开始计算
+				next = task.schedule.Next(t.timeOf(t.wheel.currentTime))
+			}
+			if !next.IsZero() && t.ticksOf(next) <= t.wheel.currentTime {
+				// 下一次执行时间没有前进，例如间隔为 0，结束任务避免在同一个 tick 中无限循环
+				next = time.Time{}
+			}
+		}
+		// 先更新任务状态再执行，任务中看到的 NextRun 已经是下一次
+		task.setNext(next)
+		if next.IsZero() {
+			t.tasks.CompareAndDelete(task.ID, task)
+		}
+		t.dispatch(task)
+		if next.IsZero() {
+			return
+		}
+		task.expiration = t.ticksOf(next)
+	}
+}
+
+// dispatch 按任务的 overrun 策略提交一次执行
+func (t *TimeWheel) dispatch(task *Task) {
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	if task.cancelled {
+		return
+	}
+	if task.active > 0 {
+		switch task.overrun {
+		case OverrunSkip:
+			return
+		case OverrunQueue:
+			task.pending++
+			return
+		}
+	}
+	t.submit(task)
+}
+
+// submit 把一次执行提交到协程池，调用方需持有 task.mu
+func (t *TimeWheel) submit(task *Task) {
+	if !t.pool.submit(func() { t.execute(task) }) {
+		return // 已经停止
+	}
+	task.active++
+	task.runs.Add(1)
 }
 
-func (t *TimeWheel) delTask(id string) {
-	if val, ok := t.tasks.Load(id); ok {
-		task := val.(*list.Element).Value.(*Task)
-		t.slots[task.slots].Remove(val.(*list.Element))
-		t.tasks.Delete(task.ID)
+// execute 执行一次任务，结束后执行 OverrunQueue 策略下排队的下一次
+func (t *TimeWheel) execute(task *Task) {
+	if err := task.run(t.ctx); err != nil && t.onError != nil {
+		t.onError(task.ID, err)
+	}
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	task.active--
+	if task.pending > 0 && !task.cancelled {
+		task.pending--
+		t.submit(task)
 	}
 }
+
+// delTask 把已经从 tasks 中删除的任务移出时间轮
+func (t *TimeWheel) delTask(task *Task) {
+	task.times = 0 // 任务正在执行时不再重新加入
+	if task.bucket != nil {
+		task.bucket.tasks.Remove(task.elem)
+		task.bucket, task.elem = nil, nil
+	}
+}
+
+// resetTask 修改任务的下一次执行时间
+func (t *TimeWheel) resetTask(req resetRequest) {
+	if val, ok := t.tasks.Load(req.task.ID); !ok || val != req.task {
+		return // 已经结束或取消
+	}
+	if req.task.bucket != nil {
+		req.task.bucket.tasks.Remove(req.task.elem)
+		req.task.bucket, req.task.elem = nil, nil
+	}
+	req.task.setNext(req.next)
+	t.addTask(req.task)
+}
+
 func (t *TimeWheel) run() {
 	for {
+		// 只在最早的槽到期时唤醒
+		var timer clock.Timer
+		var expired <-chan time.Time
+		if next := t.queue.peek(); next != nil {
+			timer = t.clock.NewTimer(t.timeOf(next.expiration).Sub(t.clock.Now()))
+			expired = timer.C()
+		}
 		select {
-		case _ = <-t.ticker.C:
-			t.runTask()
+		case <-expired:
+			t.expire(t.currentTick())
 		case task := <-t.addTaskCh:
-			t.addTask(task, true)
-		case id := <-t.removeTaskCh:
-			t.delTask(id)
-		case _ = <-t.closeCh:
-			t.ticker.Stop()
+			t.addTask(task)
+		case task := <-t.removeTaskCh:
+			t.delTask(task)
+		case req := <-t.resetTaskCh:
+			t.resetTask(req)
+		case ch := <-t.snapshotCh:
+			ch <- t.snapshot()
+		case <-t.closeCh:
+			if timer != nil {
+				timer.Stop()
+			}
 			return
 		}
+		if timer != nil {
+			timer.Stop()
+		}
 	}
 }
 
-func (t *TimeWheel) runTask() {
-	tasks := t.slots[t.currentSlots]
-	if tasks != nil {
-		for item := tasks.Front(); item != nil; item = item.Next() {
-			task := item.Value.(*Task)
-			if task.circle > 0 {
-				task.circle--
-				continue
-			}
-			go task.job(task.ID)
-			t.tasks.Delete(task.ID)
-			tasks.Remove(item)
-			if task.times == -1 {
-				t.addTask(task, false)
-			} else {
-				if task.times-1 > 0 {
-					task.times--
-					t.addTask(task, false)
-				}
-			}
+// expire 按到期顺序取出到 now 为止到期的所有槽，槽中的任务重新放入时间轮，降级到下层或者到期执行
+func (t *TimeWheel) expire(now int64) {
+	for next := t.queue.peek(); next != nil && next.expiration <= now; next = t.queue.peek() {
+		b := heap.Pop(&t.queue).(*bucket)
+		t.wheel.advanceClock(b.expiration)
+		tasks := b.tasks
+		b.tasks, b.expiration = list.New(), -1
+		for e := tasks.Front(); e != nil; e = e.Next() {
+			task := e.Value.(*Task)
+			task.bucket, task.elem = nil, nil
+			t.schedule(task)
 		}
 	}
-	t.currentSlots = (t.currentSlots + 1) % t.slotsNum
 }
 
-func (t *TimeWheel) getInitSlots() int64 {
-	return time.Now().Unix() % t.slotsNum
+// currentTick 返回当前时间所在的 tick
+func (t *TimeWheel) currentTick() int64 {
+	return int64(t.clock.Since(t.startTime) / t.interval)
 }
 
-func (t *TimeWheel) getCircleAndSlots(delay time.Duration, first bool) (circle, slots int64) {
-	delaySed := int64(delay.Seconds())
-	intervalSed := int64(t.interval.Seconds())
-	circle = delaySed / intervalSed / t.slotsNum
-	slots = delaySed - (t.slotsNum * intervalSed * circle) + t.currentSlots
-	if slots == t.currentSlots && circle > 0 {
-		circle--
-	}
-	//第一次加入时 当前秒（currentSlots）还未执行，比如当前是第一秒的slot(0) 延迟5秒计算得出为5 （0～5有6格所有需要-1）
-	//第二次加入时 当前秒（currentSlots）已经执行，就不需要-1
-	if slots > 0 && first {
-		slots--
+// ticksOf 返回时间 at 所在的 tick，向上取整，保证任务不会提前执行
+func (t *TimeWheel) ticksOf(at time.Time) int64 {
+	d := at.Sub(t.startTime)
+	if d <= 0 {
+		return 0
 	}
-	return
+	return int64((d + t.interval - 1) / t.interval)
+}
+
+// timeOf 返回第 tick 个 tick 的时间
+func (t *TimeWheel) timeOf(tick int64) time.Time {
+	return t.startTime.Add(time.Duration(tick) * t.interval)
 }
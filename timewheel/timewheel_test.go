@@ -1,246 +1,626 @@
 package timewheel
 
 import (
-	"sync"
+	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"GoTools/clock"
 )
 
-// 测试单个任务正常执行
-func TestSingleTask(t *testing.T) {
-	// 创建时间轮：1秒间隔，60个槽
-	tw, err := NewTimeWheel(time.Second, 60)
+// newFakeTimeWheel 创建使用 FakeClock 的时间轮，测试通过推进时间触发任务，无需真实等待
+func newFakeTimeWheel(t *testing.T, interval time.Duration, slotsNum int64) (*TimeWheel, *clock.FakeClock) {
+	t.Helper()
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	tw, err := NewTimeWheel(interval, slotsNum, WithClock(fc))
 	if err != nil {
 		t.Fatalf("创建时间轮失败: %v", err)
 	}
+	return tw, fc
+}
+
+// tick 把时间推进 n 个间隔，每次推进后等待时间轮读走这一次 tick，避免 tick 被丢弃
+func tick(fc *clock.FakeClock, interval time.Duration, n int) {
+	for i := 0; i < n; i++ {
+		fc.Advance(interval)
+		fc.WaitIdle()
+	}
+}
+
+// waitExec 等待任务执行一次，超时则失败
+func waitExec(t *testing.T, done <-chan string) string {
+	t.Helper()
+	select {
+	case key := <-done:
+		return key
+	case <-time.After(time.Second):
+		t.Fatal("任务执行超时，未在预期时间内执行")
+		return ""
+	}
+}
+
+// 确认任务没有执行，任务在单独的协程中运行，留出一点调度时间
+func assertNotExec(t *testing.T, done <-chan string) {
+	t.Helper()
+	select {
+	case key := <-done:
+		t.Fatalf("任务 %s 不应执行", key)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// 测试单个任务正常执行
+func TestSingleTask(t *testing.T) {
+	// 创建时间轮：1秒间隔，60个槽
+	tw, fc := newFakeTimeWheel(t, time.Second, 60)
 	defer tw.Stop()
 
-	var (
-		execCount int32
-		wg        sync.WaitGroup
-	)
-	wg.Add(1) // 等待1次执行
+	done := make(chan string, 1)
 
 	// 添加任务：延迟1秒，执行1次
 	taskID := "single-task"
 	tw.AddTask(taskID, time.Second, func(key string) {
-		defer wg.Done()
-		execCount++
-		if key != taskID {
-			t.Errorf("任务ID不匹配，预期 %s, 实际 %s", taskID, key)
-		}
+		done <- key
 	}, 1)
 
-	// 等待任务执行或超时（3秒超时，允许系统误差）
-	timeout := time.After(3 * time.Second)
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		if execCount != 1 {
-			t.Errorf("任务执行次数错误，预期1次，实际%d次", execCount)
-		}
-	case <-timeout:
-		t.Fatal("任务执行超时，未在预期时间内执行")
+	tick(fc, time.Second, 1)
+	if key := waitExec(t, done); key != taskID {
+		t.Errorf("任务ID不匹配，预期 %s, 实际 %s", taskID, key)
 	}
+
+	// 只执行一次
+	tick(fc, time.Second, 60)
+	assertNotExec(t, done)
+}
+
+// 测试任务不会提前执行
+func TestTaskNotEarly(t *testing.T) {
+	tw, fc := newFakeTimeWheel(t, time.Second, 60)
+	defer tw.Stop()
+
+	done := make(chan string, 1)
+	tw.AddTask("late-task", 5*time.Second, func(key string) {
+		done <- key
+	}, 1)
+
+	tick(fc, time.Second, 4)
+	assertNotExec(t, done)
+	tick(fc, time.Second, 1)
+	waitExec(t, done)
 }
 
 // 测试重复任务（有限次数）
 func TestRepeatTask(t *testing.T) {
 	// 创建时间轮：1秒间隔，60个槽
-	tw, err := NewTimeWheel(time.Second, 60)
-	if err != nil {
-		t.Fatalf("创建时间轮失败: %v", err)
-	}
+	tw, fc := newFakeTimeWheel(t, time.Second, 60)
 	defer tw.Stop()
 
-	var (
-		execCount int32
-		mu        sync.Mutex
-		wg        sync.WaitGroup
-	)
+	var execCount atomic.Int32
+	done := make(chan string, 1)
 	repeatTimes := int64(3)
-	wg.Add(int(repeatTimes)) // 等待3次执行
 
 	// 添加任务：延迟1秒，执行3次
 	taskID := "repeat-task"
 	tw.AddTask(taskID, time.Second, func(key string) {
-		defer wg.Done()
-		mu.Lock()
-		execCount++
-		mu.Unlock()
+		execCount.Add(1)
+		done <- key
 	}, repeatTimes)
 
-	// 等待所有执行完成或超时（5秒超时）
-	timeout := time.After(5 * time.Second)
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		mu.Lock()
-		defer mu.Unlock()
-		if execCount != int32(repeatTimes) {
-			t.Errorf("重复任务执行次数错误，预期%d次，实际%d次", repeatTimes, execCount)
-		}
-	case <-timeout:
-		t.Fatal("重复任务执行超时")
+	for i := int64(0); i < repeatTimes; i++ {
+		tick(fc, time.Second, 1)
+		waitExec(t, done)
+	}
+	tick(fc, time.Second, 5)
+	assertNotExec(t, done)
+	if n := execCount.Load(); n != int32(repeatTimes) {
+		t.Errorf("重复任务执行次数错误，预期%d次，实际%d次", repeatTimes, n)
 	}
 }
 
 // 测试无限重复任务（times=-1）
 func TestInfiniteTask(t *testing.T) {
-	// 创建时间轮：1秒间隔，60个槽
-	tw, err := NewTimeWheel(time.Second, 60)
-	if err != nil {
-		t.Fatalf("创建时间轮失败: %v", err)
-	}
+	// 创建时间轮：1秒间隔，3个槽，执行次数超过一圈
+	tw, fc := newFakeTimeWheel(t, time.Second, 3)
 	defer tw.Stop()
 
-	var (
-		execCount int32
-		mu        sync.Mutex
-		wg        sync.WaitGroup
-	)
-	expectedExec := 2 // 预期执行2次后停止时间轮
-	wg.Add(expectedExec)
+	done := make(chan string, 1)
 
 	// 添加任务：延迟1秒，无限执行
 	taskID := "infinite-task"
 	tw.AddTask(taskID, time.Second, func(key string) {
-		mu.Lock()
-		execCount++
-		current := execCount
-		mu.Unlock()
-
-		if current <= int32(expectedExec) {
-			wg.Done()
-		}
+		done <- key
 	}, -1)
 
-	// 等待2次执行后停止时间轮
-	timeout := time.After(4 * time.Second)
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		tw.Stop() // 执行2次后停止
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		mu.Lock()
-		defer mu.Unlock()
-		if execCount != int32(expectedExec) {
-			t.Errorf("无限任务执行次数错误，预期%d次，实际%d次", expectedExec, execCount)
-		}
-	case <-timeout:
-		t.Fatal("无限任务执行超时")
+	for i := 0; i < 10; i++ {
+		tick(fc, time.Second, 1)
+		waitExec(t, done)
 	}
 }
 
 // 测试任务删除（确保删除后不执行）
 func TestRemoveTask(t *testing.T) {
-	// 创建时间轮：2秒间隔，60个槽（延迟更长，确保有时间删除）
-	tw, err := NewTimeWheel(2*time.Second, 60)
-	if err != nil {
-		t.Fatalf("创建时间轮失败: %v", err)
-	}
+	// 创建时间轮：2秒间隔，60个槽
+	tw, fc := newFakeTimeWheel(t, 2*time.Second, 60)
 	defer tw.Stop()
 
-	var execCount int32
+	done := make(chan string, 1)
 
-	// 添加任务：延迟4秒（确保有时间删除）
+	// 添加任务：延迟4秒
 	taskID := "remove-task"
 	tw.AddTask(taskID, 4*time.Second, func(key string) {
-		execCount++ // 如果执行则计数
+		done <- key
 	}, 1)
 
-	// 立即删除任务
-	time.Sleep(1 * time.Second) // 等待任务被添加到时间轮
-	err = tw.RemoveTask(taskID)
-	if err != nil {
+	tick(fc, 2*time.Second, 1)
+	if err := tw.RemoveTask(taskID); err != nil {
 		t.Fatalf("删除任务失败: %v", err)
 	}
-
-	// 等待足够长的时间（超过延迟时间），检查是否执行
-	time.Sleep(5 * time.Second)
-
-	if execCount != 0 {
-		t.Errorf("已删除的任务不应执行，实际执行了%d次", execCount)
+	if err := tw.RemoveTask(taskID); err == nil {
+		t.Error("重复删除应返回错误")
 	}
+
+	// 推进超过延迟时间，检查是否执行
+	tick(fc, 2*time.Second, 3)
+	assertNotExec(t, done)
 }
 
 // 测试长延迟任务（超过单圈最大时间）
 func TestLongDelayTask(t *testing.T) {
 	// 时间轮配置：1秒间隔，3个槽（单圈最大延迟3秒）
-	tw, err := NewTimeWheel(time.Second, 3)
-	if err != nil {
-		t.Fatalf("创建时间轮失败: %v", err)
-	}
+	tw, fc := newFakeTimeWheel(t, time.Second, 3)
 	defer tw.Stop()
 
-	var (
-		execCount int32
-		wg        sync.WaitGroup
-	)
-	wg.Add(1)
+	done := make(chan string, 1)
 
 	// 延迟4秒（超过单圈3秒，需要多转1圈）
 	taskID := "long-delay-task"
 	tw.AddTask(taskID, 4*time.Second, func(key string) {
-		defer wg.Done()
-		execCount++
+		done <- key
 	}, 1)
 
-	// 等待执行或超时（6秒超时）
-	timeout := time.After(6 * time.Second)
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
+	tick(fc, time.Second, 3)
+	assertNotExec(t, done)
+	tick(fc, time.Second, 1)
+	waitExec(t, done)
+}
 
-	select {
-	case <-done:
-		if execCount != 1 {
-			t.Errorf("长延迟任务执行次数错误，预期1次，实际%d次", execCount)
+// 测试当前槽不在起点时添加跨越时间轮末尾的任务
+func TestDelayAcrossWheelEnd(t *testing.T) {
+	tw, fc := newFakeTimeWheel(t, time.Second, 3)
+	defer tw.Stop()
+
+	tick(fc, time.Second, 2)
+	for delay := 1; delay <= 7; delay++ {
+		done := make(chan string, 1)
+		tw.AddTask("across-end", time.Duration(delay)*time.Second, func(key string) {
+			done <- key
+		}, 1)
+		tick(fc, time.Second, delay-1)
+		assertNotExec(t, done)
+		tick(fc, time.Second, 1)
+		waitExec(t, done)
+	}
+}
+
+// 测试毫秒精度的任务
+func TestMillisecondTask(t *testing.T) {
+	tw, fc := newFakeTimeWheel(t, time.Millisecond, 10)
+	defer tw.Stop()
+
+	done := make(chan string, 3)
+	for _, delay := range []time.Duration{5 * time.Millisecond, 25 * time.Millisecond, 250 * time.Millisecond} {
+		tw.AddTask(delay.String(), delay, func(key string) {
+			done <- key
+		}, 1)
+	}
+
+	fc.Advance(4 * time.Millisecond)
+	assertNotExec(t, done)
+	fc.Advance(time.Millisecond)
+	if key := waitExec(t, done); key != "5ms" {
+		t.Errorf("期望 5ms 的任务先执行，实际 %s", key)
+	}
+	// 25ms 的任务在第二层，到期前降级到底层
+	fc.Advance(19 * time.Millisecond)
+	assertNotExec(t, done)
+	fc.Advance(time.Millisecond)
+	if key := waitExec(t, done); key != "25ms" {
+		t.Errorf("期望 25ms 的任务执行，实际 %s", key)
+	}
+	// 250ms 的任务在第三层，一次推进很长时间也要按时执行
+	fc.Advance(224 * time.Millisecond)
+	assertNotExec(t, done)
+	fc.Advance(time.Millisecond)
+	if key := waitExec(t, done); key != "250ms" {
+		t.Errorf("期望 250ms 的任务执行，实际 %s", key)
+	}
+}
+
+// 测试很长的延迟逐层降级，不需要很大的槽数组
+func TestCascadeLongDelay(t *testing.T) {
+	tw, fc := newFakeTimeWheel(t, time.Millisecond, 8)
+	defer tw.Stop()
+
+	done := make(chan string, 2)
+	week := 7 * 24 * time.Hour
+	tw.AddTask("week", week, func(key string) {
+		done <- key
+	}, 1)
+	tw.AddTask("day", 24*time.Hour+time.Millisecond, func(key string) {
+		done <- key
+	}, 1)
+
+	for _, step := range []time.Duration{time.Hour, 23 * time.Hour, time.Millisecond} {
+		fc.Advance(step)
+		if step != time.Millisecond {
+			assertNotExec(t, done)
 		}
-	case <-timeout:
-		t.Fatal("长延迟任务执行超时")
+	}
+	if key := waitExec(t, done); key != "day" {
+		t.Errorf("期望 day 的任务执行，实际 %s", key)
+	}
+	fc.Advance(week - 24*time.Hour - 2*time.Millisecond)
+	assertNotExec(t, done)
+	fc.Advance(time.Millisecond)
+	if key := waitExec(t, done); key != "week" {
+		t.Errorf("期望 week 的任务执行，实际 %s", key)
 	}
 }
 
 // 测试时间轮停止后任务不再执行
 func TestStopTimeWheel(t *testing.T) {
-	tw, err := NewTimeWheel(time.Second, 60)
-	if err != nil {
-		t.Fatalf("创建时间轮失败: %v", err)
-	}
+	tw, fc := newFakeTimeWheel(t, time.Second, 60)
 
-	var execCount int32
+	done := make(chan string, 1)
 
 	// 添加任务：延迟2秒执行
 	taskID := "stop-test-task"
 	tw.AddTask(taskID, 2*time.Second, func(key string) {
-		execCount++
+		done <- key
 	}, 1)
 
 	// 1秒后停止时间轮（在任务执行前）
-	time.Sleep(1 * time.Second)
+	tick(fc, time.Second, 1)
 	tw.Stop()
 
-	// 再等待2秒，确认任务不执行
-	time.Sleep(2 * time.Second)
+	// 再推进2秒，确认任务不执行
+	fc.Advance(2 * time.Second)
+	assertNotExec(t, done)
+}
+
+// 测试参数校验
+func TestInvalidArgs(t *testing.T) {
+	if _, err := NewTimeWheel(0, 60); err == nil {
+		t.Error("间隔为0应返回错误")
+	}
+	if _, err := NewTimeWheel(time.Second, 0); err == nil {
+		t.Error("槽数为0应返回错误")
+	}
+
+	tw, _ := newFakeTimeWheel(t, time.Second, 60)
+	defer tw.Stop()
+	job := func(key string) {}
+	if err := tw.AddTask("", time.Second, job); err == nil {
+		t.Error("空ID应返回错误")
+	}
+	if err := tw.AddTask("short", time.Millisecond, job); err == nil {
+		t.Error("延迟小于间隔应返回错误")
+	}
+	if err := tw.AddTask("dup", time.Second, job); err != nil {
+		t.Fatalf("添加任务失败: %v", err)
+	}
+	if err := tw.AddTask("dup", time.Second, job); err == nil {
+		t.Error("重复ID应返回错误")
+	}
+}
+
+// 测试任务句柄的 Reset、Cancel、NextRun 和 Runs
+func TestTaskHandle(t *testing.T) {
+	tw, fc := newFakeTimeWheel(t, time.Second, 60)
+	defer tw.Stop()
+
+	done := make(chan string, 1)
+	job := func(ctx context.Context, key string) error {
+		done <- key
+		return errors.New("job failed")
+	}
+	h, err := tw.Schedule("handle", 5*time.Second, job, WithTimes(-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.NextRun().Equal(time.Unix(5, 0)) {
+		t.Errorf("期望下一次在第5秒执行，实际 %v", h.NextRun())
+	}
+
+	// 提前到2秒后执行，之后仍然每5秒执行一次
+	tick(fc, time.Second, 1)
+	if !h.Reset(2 * time.Second) {
+		t.Fatal("Reset 应成功")
+	}
+	tick(fc, time.Second, 1)
+	assertNotExec(t, done)
+	tick(fc, time.Second, 1)
+	waitExec(t, done)
+	if h.Runs() != 1 || !h.NextRun().Equal(time.Unix(8, 0)) {
+		t.Errorf("期望执行1次、下一次在第8秒，实际 %d、%v", h.Runs(), h.NextRun())
+	}
+	if h.Err() == nil {
+		t.Error("应记录任务返回的错误")
+	}
+
+	if !h.Cancel() || h.Cancel() {
+		t.Error("第一次 Cancel 应成功，重复 Cancel 应失败")
+	}
+	if !h.NextRun().IsZero() || h.Reset(time.Second) {
+		t.Error("取消后没有下一次执行，也不能 Reset")
+	}
+	tick(fc, time.Second, 10)
+	assertNotExec(t, done)
+	if _, ok := tw.Task("handle"); ok {
+		t.Error("取消后任务应被删除")
+	}
+}
+
+// 测试 AddAt 和 AddCron
+func TestAddAtAndCron(t *testing.T) {
+	tw, fc := newFakeTimeWheel(t, time.Second, 60)
+	defer tw.Stop()
+
+	done := make(chan string, 1)
+	job := func(ctx context.Context, key string) error {
+		done <- key
+		return nil
+	}
+	at, _ := tw.AddAt("at", time.Unix(3, 0), job)
+	tick(fc, time.Second, 2)
+	assertNotExec(t, done)
+	tick(fc, time.Second, 1)
+	if key := waitExec(t, done); key != "at" {
+		t.Errorf("期望 at 执行，实际 %s", key)
+	}
+	if !at.NextRun().IsZero() || at.Cancel() {
+		t.Error("只执行一次的任务执行后应结束")
+	}
 
-	if execCount != 0 {
-		t.Errorf("时间轮停止后任务不应执行，实际执行了%d次", execCount)
+	if _, err := tw.AddCron("bad", "* * *", job); err == nil {
+		t.Error("错误的 cron 表达式应返回错误")
+	}
+	// 每10秒执行一次，当前为第3秒
+	cron, err := tw.AddCron("cron", "*/10 * * * * *", job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []int64{10, 20, 30} {
+		if !cron.NextRun().Equal(time.Unix(want, 0)) {
+			t.Fatalf("期望下一次在第%d秒执行，实际 %v", want, cron.NextRun())
+		}
+		fc.Set(time.Unix(want, 0).Add(-time.Second))
+		assertNotExec(t, done)
+		fc.Set(time.Unix(want, 0))
+		waitExec(t, done)
+	}
+}
+
+// 测试停止时间轮会取消正在执行的任务
+func TestStopCancelsJobs(t *testing.T) {
+	tw, fc := newFakeTimeWheel(t, time.Second, 60)
+
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+	tw.Schedule("long", time.Second, func(ctx context.Context, key string) error {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return ctx.Err()
+	})
+	tick(fc, time.Second, 1)
+	<-started
+	tw.Stop()
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("期望 context.Canceled，实际 %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("停止后任务的 ctx 应被取消")
+	}
+	if _, err := tw.Schedule("after-stop", time.Second, func(ctx context.Context, key string) error { return nil }); !errors.Is(err, ErrStopped) {
+		t.Errorf("停止后添加任务期望 ErrStopped，实际 %v", err)
+	}
+}
+
+// 测试任务 panic 不会导致进程崩溃，错误交给回调
+func TestPanicRecovery(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	errs := make(chan error, 1)
+	tw, _ := NewTimeWheel(time.Second, 60, WithClock(fc), WithErrorHandler(func(id string, err error) {
+		errs <- err
+	}))
+	defer tw.Stop()
+
+	h, _ := tw.Schedule("panic", time.Second, func(ctx context.Context, key string) error {
+		panic("boom")
+	})
+	tick(fc, time.Second, 1)
+	select {
+	case err := <-errs:
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+			t.Errorf("期望 *PanicError，实际 %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panic 时应调用错误回调")
+	}
+	if !errors.As(h.Err(), new(*PanicError)) {
+		t.Errorf("Err 应返回 panic 的错误，实际 %v", h.Err())
+	}
+}
+
+// 测试上一次执行没有结束时的三种处理方式
+func TestOverrunPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		policy  OverrunPolicy
+		running int32 // 连续到期3次后同时执行的数量
+		total   int32 // 放行后总的执行次数
+	}{
+		{"concurrent", OverrunConcurrent, 3, 3},
+		{"skip", OverrunSkip, 1, 1},
+		{"queue", OverrunQueue, 1, 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tw, fc := newFakeTimeWheel(t, time.Second, 60)
+			defer tw.Stop()
+
+			var running, total atomic.Int32
+			release := make(chan struct{})
+			h, _ := tw.Schedule("overrun", time.Second, func(ctx context.Context, key string) error {
+				running.Add(1)
+				defer running.Add(-1)
+				total.Add(1)
+				<-release
+				return nil
+			}, WithTimes(3), WithOverrun(tt.policy))
+
+			tick(fc, time.Second, 3)
+			for h.NextRun() != (time.Time{}) {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(20 * time.Millisecond)
+			if n := running.Load(); n != tt.running {
+				t.Errorf("期望同时执行%d个，实际%d个", tt.running, n)
+			}
+			close(release)
+			for running.Load() != 0 || h.Runs() != int64(total.Load()) {
+				time.Sleep(time.Millisecond)
+			}
+			if n := total.Load(); n != tt.total {
+				t.Errorf("期望一共执行%d次，实际%d次", tt.total, n)
+			}
+		})
+	}
+}
+
+// 测试协程池限制同时执行的任务数
+func TestWorkerPool(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	tw, _ := NewTimeWheel(time.Second, 60, WithClock(fc), WithWorkers(2))
+	defer tw.Stop()
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	done := make(chan string, 5)
+	for i := 0; i < 5; i++ {
+		tw.Schedule(string(rune('a'+i)), time.Second, func(ctx context.Context, key string) error {
+			n := running.Add(1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			<-release
+			running.Add(-1)
+			done <- key
+			return nil
+		})
+	}
+	tick(fc, time.Second, 1)
+	for running.Load() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	for i := 0; i < 5; i++ {
+		waitExec(t, done)
+	}
+	if p := peak.Load(); p != 2 {
+		t.Errorf("同时执行的任务不应超过2个，实际 %d", p)
+	}
+}
+
+// 测试 Stop 等待正在执行的任务，超时返回错误
+func TestStopTimeout(t *testing.T) {
+	fc := clock.NewFakeClock(time.Unix(0, 0))
+	tw, _ := NewTimeWheel(time.Second, 60, WithClock(fc), WithStopTimeout(5*time.Second))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	tw.Schedule("stubborn", time.Second, func(ctx context.Context, key string) error {
+		close(started)
+		<-release // 不响应 ctx 的任务
+		return nil
+	})
+	tick(fc, time.Second, 1)
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- tw.Stop() }()
+	fc.BlockUntil(1)
+	select {
+	case err := <-stopped:
+		t.Fatalf("任务结束前 Stop 不应返回，实际 %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	fc.Advance(5 * time.Second)
+	if err := <-stopped; !errors.Is(err, ErrStopTimeout) {
+		t.Errorf("期望 ErrStopTimeout，实际 %v", err)
+	}
+	close(release)
+}
+
+// stuckSchedule 下一次执行时间总是等于当前时间
+type stuckSchedule struct{}
+
+func (stuckSchedule) Next(t time.Time) time.Time { return t }
+
+// 测试 AddAt 只能执行一次，下一次执行时间不前进的任务执行一次后结束，不会卡住时间轮
+func TestNoProgressSchedule(t *testing.T) {
+	tw, fc := newFakeTimeWheel(t, time.Second, 60)
+
+	done := make(chan string, 10)
+	job := func(ctx context.Context, key string) error {
+		done <- key
+		return nil
+	}
+	for _, times := range []int64{5, -1} {
+		if _, err := tw.AddAt("at", time.Unix(1, 0), job, WithTimes(times)); err == nil {
+			t.Errorf("AddAt 使用 WithTimes(%d) 应返回错误", times)
+		}
+	}
+
+	h, err := tw.AddSchedule("stuck", stuckSchedule{}, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tick(fc, time.Second, 1)
+	waitExec(t, done)
+	assertNotExec(t, done)
+	if !h.NextRun().IsZero() {
+		t.Error("下一次执行时间不前进的任务应结束")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		tw.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("时间轮应能正常停止")
+	}
+}
+
+// 槽还在延迟队列中时被下一圈的任务复用，不能重复加入队列
+func TestBucketQueuedOnce(t *testing.T) {
+	w := newWheel(1, 10, 0)
+	var queue bucketQueue
+	if !w.add(&Task{expiration: 5}, &queue) {
+		t.Fatal("未到期的任务应放入时间轮")
+	}
+	w.advanceClock(6) // 时间轮越过了还在队列中的槽
+	if !w.add(&Task{expiration: 15}, &queue) {
+		t.Fatal("未到期的任务应放入时间轮")
+	}
+	if queue.Len() != 1 {
+		t.Errorf("同一个槽应只在队列中出现一次，实际 %d 个", queue.Len())
 	}
 }
@@ -0,0 +1,116 @@
+package timewheel
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// bucket 时间轮的一个槽，expiration 为槽的到期 tick（槽覆盖的时间范围的起点），-1 表示槽不在延迟队列中
+type bucket struct {
+	expiration int64
+	tasks      *list.List // 元素为 *Task
+	index      int        // 在延迟队列中的下标
+}
+
+func newBucket() *bucket {
+	return &bucket{expiration: -1, tasks: list.New(), index: -1}
+}
+
+// wheel 一层时间轮，参照 Kafka 的分层时间轮：
+// 每层有 wheelSize 个槽，上一层的一个槽覆盖下一层的一整圈，超出本层范围的任务交给上一层（overflow）
+// tick、interval 和 currentTime 都以最底层的 tick 为单位
+type wheel struct {
+	tick        int64 // 每个槽覆盖的 tick 数
+	wheelSize   int64
+	interval    int64 // 一圈覆盖的 tick 数
+	currentTime int64 // 本层当前时间，总是 tick 的整数倍
+	buckets     []*bucket
+	overflow    *wheel // 上一层，第一次需要时创建
+}
+
+func newWheel(tick, wheelSize, startTime int64) *wheel {
+	w := &wheel{
+		tick:        tick,
+		wheelSize:   wheelSize,
+		interval:    tick * wheelSize,
+		currentTime: startTime - startTime%tick,
+		buckets:     make([]*bucket, wheelSize),
+	}
+	for i := range w.buckets {
+		w.buckets[i] = newBucket()
+	}
+	return w
+}
+
+// add 把任务放进覆盖其到期时间的槽，槽的到期时间变化时加入延迟队列，任务已经到期时返回 false
+func (w *wheel) add(task *Task, queue *bucketQueue) bool {
+	expiration := task.expiration
+	switch {
+	case expiration < w.currentTime+w.tick:
+		return false
+	case expiration < w.currentTime+w.interval:
+		virtualID := expiration / w.tick
+		b := w.buckets[virtualID%w.wheelSize]
+		task.bucket, task.elem = b, b.tasks.PushBack(task)
+		// 同一个槽在到期被取出之前只会存放同一圈的任务，到期时间只会在取出后变化
+		// 槽仍在队列中时只调整位置，避免同一个槽被重复加入
+		if b.expiration != virtualID*w.tick {
+			b.expiration = virtualID * w.tick
+			if b.index >= 0 {
+				heap.Fix(queue, b.index)
+			} else {
+				heap.Push(queue, b)
+			}
+		}
+		return true
+	default:
+		if w.overflow == nil {
+			w.overflow = newWheel(w.interval, w.wheelSize, w.currentTime)
+		}
+		return w.overflow.add(task, queue)
+	}
+}
+
+// advanceClock 把本层和所有上层的当前时间推进到 timestamp
+func (w *wheel) advanceClock(timestamp int64) {
+	if timestamp >= w.currentTime+w.tick {
+		w.currentTime = timestamp - timestamp%w.tick
+		if w.overflow != nil {
+			w.overflow.advanceClock(w.currentTime)
+		}
+	}
+}
+
+// bucketQueue 按到期时间排序的槽，相当于 Kafka 的 DelayQueue，时间轮只在最早的槽到期时才被唤醒，
+// 不需要每个 tick 都空转，因此 tick 可以很小
+type bucketQueue []*bucket
+
+func (q bucketQueue) Len() int           { return len(q) }
+func (q bucketQueue) Less(i, j int) bool { return q[i].expiration < q[j].expiration }
+func (q bucketQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+
+func (q *bucketQueue) Push(x any) {
+	b := x.(*bucket)
+	b.index = len(*q)
+	*q = append(*q, b)
+}
+
+func (q *bucketQueue) Pop() any {
+	old := *q
+	b := old[len(old)-1]
+	old[len(old)-1] = nil
+	b.index = -1
+	*q = old[:len(old)-1]
+	return b
+}
+
+// peek 返回最早到期的槽，队列为空时返回 nil
+func (q bucketQueue) peek() *bucket {
+	if len(q) == 0 {
+		return nil
+	}
+	return q[0]
+}